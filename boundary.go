@@ -0,0 +1,92 @@
+package masc
+
+import (
+	"runtime/debug"
+)
+
+// RenderPanicMsg is sent by an ErrorBoundary when rendering its Child
+// panicked.
+type RenderPanicMsg struct {
+	// Value is the value the render panicked with.
+	Value interface{}
+	// Stack is the stack trace of the panic.
+	Stack []byte
+}
+
+// ErrorBoundary is a Component that contains panics in the rendering of its
+// Child, so that the rest of the page keeps working. When rendering Child
+// panics, the boundary discards the DOM nodes of its previous render, sends a
+// RenderPanicMsg and renders Fallback in their place.
+//
+// A boundary keeps rendering Fallback until its ResetKey changes, at which
+// point it tries to render Child again.
+//
+//	&masc.ErrorBoundary{
+//		Child:    &Chart{Series: m.series},
+//		Fallback: func(RenderPanicMsg) masc.ComponentOrHTML { return elem.Div(masc.Text("Chart unavailable")) },
+//		ResetKey: m.version,
+//	}
+type ErrorBoundary struct {
+	Core
+
+	// Child is the content of the boundary.
+	Child ComponentOrHTML `masc:"prop"`
+
+	// Fallback renders the content shown in place of Child after it
+	// panicked. If nil, nothing is shown.
+	Fallback func(RenderPanicMsg) ComponentOrHTML `masc:"prop"`
+
+	// ResetKey must be comparable. Changing it makes a failed boundary render
+	// Child again.
+	ResetKey interface{} `masc:"prop"`
+
+	failure   *RenderPanicMsg
+	failedKey interface{}
+}
+
+// Render implements the Component interface.
+func (b *ErrorBoundary) Render(send func(Msg)) ComponentOrHTML {
+	if b.failure == nil {
+		return b.Child
+	}
+	if b.Fallback == nil {
+		return nil
+	}
+	return b.Fallback(*b.failure)
+}
+
+// catches reports whether the boundary is about to render its Child, and so
+// must recover its panics, after resetting a failure whose ResetKey changed.
+func (b *ErrorBoundary) catches() bool {
+	if b.failure != nil && b.ResetKey != b.failedKey {
+		b.failure = nil
+		b.failedKey = nil
+	}
+	return b.failure == nil
+}
+
+// recover is deferred by renderComponent while the boundary renders its Child.
+// On a panic, it replaces the results of the render with those of Fallback.
+func (b *ErrorBoundary) recover(send func(Msg), batch *batchRenderer, nextHTML **HTML, skip *bool, pendingMounts *[]Mounter) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	msg := RenderPanicMsg{Value: r, Stack: debug.Stack()}
+	b.failure = &msg
+	b.failedKey = b.ResetKey
+
+	// The panic may have left the previous render half reconciled, so unmount
+	// it and render Fallback into new DOM nodes, which the parent swaps in.
+	if b.prevRender != nil {
+		unmount(b.prevRender)
+	}
+	b.prevRender = nil
+	b.prevRenderComponent = nil
+	*nextHTML, *skip, *pendingMounts = renderComponent(b, nil, send, batch)
+
+	if send != nil {
+		// Renders may run on the event loop, so do not block it.
+		go send(msg)
+	}
+}
@@ -0,0 +1,99 @@
+package masc
+
+import (
+	"fmt"
+	"testing"
+)
+
+type flakyComponent struct {
+	Core
+	fail      *bool
+	renders   *int
+	unmounted *int
+}
+
+func (c *flakyComponent) Render(send func(Msg)) ComponentOrHTML {
+	*c.renders++
+	if *c.fail {
+		panic("boom")
+	}
+	return Tag("div", Text("ok"))
+}
+
+func (c *flakyComponent) Unmount() { *c.unmounted++ }
+
+type boundaryBody struct {
+	Core
+	child    *flakyComponent
+	resetKey int
+}
+
+func (b *boundaryBody) Render(send func(Msg)) ComponentOrHTML {
+	return Tag("body",
+		Tag("p", Text("sibling")),
+		&ErrorBoundary{
+			Child: &flakyComponent{fail: b.child.fail, renders: b.child.renders, unmounted: b.child.unmounted},
+			Fallback: func(msg RenderPanicMsg) ComponentOrHTML {
+				return Tag("div", Text(fmt.Sprint("failed: ", msg.Value)))
+			},
+			ResetKey: b.resetKey,
+		},
+	)
+}
+
+func TestErrorBoundary(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.strings.mock(`global.Get("document").Get("readyState")`, "complete")
+	ts.strings.mock(`global.Get("document").Call("querySelector", "body").Get("nodeName")`, "BODY")
+	ts.truthies.mock(`global.Get("document").Call("querySelector", "body")`, true)
+
+	var fail bool
+	var renders, unmounted int
+	body := &boundaryBody{child: &flakyComponent{fail: &fail, renders: &renders, unmounted: &unmounted}}
+	msgs := make(chan Msg, 1)
+	send := func(msg Msg) { msgs <- msg }
+
+	batch := newBatchRenderer(nil)
+	renderBody(batch, body, send)
+	ts.record("(rendered the child)")
+
+	fail = true
+	rerender(batch, body, send)
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.invokeCallbackRequestAnimationFrame(0)
+	ts.record("(rendered the fallback)")
+
+	msg, ok := (<-msgs).(RenderPanicMsg)
+	if !ok || msg.Value != "boom" || len(msg.Stack) == 0 {
+		t.Fatalf("expected a RenderPanicMsg for the panic, got %+v", msg)
+	}
+	if unmounted != 1 {
+		t.Fatalf("expected the child to be unmounted, got %d unmounts", unmounted)
+	}
+
+	rerender(batch, body, send)
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.invokeCallbackRequestAnimationFrame(0)
+	ts.record("(kept the fallback)")
+	if renders != 2 {
+		t.Fatalf("expected the failed child not to render again, got %d renders", renders)
+	}
+
+	fail = false
+	body.resetKey++
+	rerender(batch, body, send)
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.invokeCallbackRequestAnimationFrame(0)
+	ts.record("(rendered the child again)")
+	if renders != 3 {
+		t.Fatalf("expected the child to render after the reset, got %d renders", renders)
+	}
+	select {
+	case msg := <-msgs:
+		t.Fatalf("expected a single message, got %+v", msg)
+	default:
+	}
+}
@@ -0,0 +1,213 @@
+package masc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// bridgeTypes maps the names used by JavaScript to the registered Msg types.
+var bridgeTypes = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+}{byName: make(map[string]reflect.Type)}
+
+// RegisterMsg makes the type of msg available to JavaScript under name, so
+// that host pages can send it to programs with a JS bridge, see WithJSBridge.
+// The JSON sent by JavaScript is decoded into a new value of the type with
+// encoding/json. RegisterMsg panics if name is already registered.
+//
+// Example:
+//
+//	type SelectMsg struct {
+//	    ID string `json:"id"`
+//	}
+//
+//	func init() {
+//	    masc.RegisterMsg("select", SelectMsg{})
+//	}
+func RegisterMsg(name string, msg Msg) {
+	if msg == nil {
+		panic("masc: cannot register nil message as " + name)
+	}
+	bridgeTypes.Lock()
+	defer bridgeTypes.Unlock()
+	if _, ok := bridgeTypes.byName[name]; ok {
+		panic("masc: message " + name + " is already registered")
+	}
+	bridgeTypes.byName[name] = reflect.TypeOf(msg)
+}
+
+// bridgeEnvelope is the JSON form of the messages exchanged with JavaScript.
+type bridgeEnvelope struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// decodeBridgeMsg decodes a message sent by JavaScript into its registered
+// type.
+func decodeBridgeMsg(data []byte) (Msg, error) {
+	var env bridgeEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	bridgeTypes.RLock()
+	t, ok := bridgeTypes.byName[env.Type]
+	bridgeTypes.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown message type %q", env.Type)
+	}
+
+	pointer := t.Kind() == reflect.Ptr
+	if pointer {
+		t = t.Elem()
+	}
+	v := reflect.New(t)
+	if len(env.Data) > 0 {
+		if err := json.Unmarshal(env.Data, v.Interface()); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", env.Type, err)
+		}
+	}
+	if pointer {
+		return v.Interface(), nil
+	}
+	return v.Elem().Interface(), nil
+}
+
+// EmitJS returns a command that sends an event to the JavaScript subscribers
+// of the program's JS bridge, see WithJSBridge. data is encoded with
+// encoding/json. Without a JS bridge the event is dropped.
+//
+// Example:
+//
+//	return m, masc.EmitJS("selected", map[string]string{"id": m.selected})
+func EmitJS(event string, data interface{}) Cmd {
+	return func() Msg {
+		return emitJSMsg{event: event, data: data}
+	}
+}
+
+// emitJSMsg tells the event loop to send an event to the JS bridge.
+type emitJSMsg struct {
+	event string
+	data  interface{}
+}
+
+// encodeBridgeEvent returns the JSON form of an event emitted by EmitJS.
+func encodeBridgeEvent(msg emitJSMsg) (string, error) {
+	data, err := json.Marshal(msg.data)
+	if err != nil {
+		return "", fmt.Errorf("encoding %s: %w", msg.event, err)
+	}
+	env, err := json.Marshal(bridgeEnvelope{Type: msg.event, Data: data})
+	if err != nil {
+		return "", err
+	}
+	return string(env), nil
+}
+
+// jsBridge is the object through which JavaScript talks to a program.
+type jsBridge struct {
+	name string
+
+	// mtx guards subscribers and funcs, which change as JavaScript
+	// subscribes while the event loop emits events.
+	mtx         sync.Mutex
+	subscribers []jsObject
+	funcs       []jsFunc
+
+	// stop stops sending the messages received from JavaScript.
+	stop context.CancelFunc
+}
+
+// install registers the bridge as window[name], sending the messages it
+// receives to send, in order, until ctx is done or the bridge is uninstalled.
+//
+// window[name].send(json) accepts a message as a JSON string of the form
+// {"type": name, "data": value}. It returns null, or an error message if the
+// message was not understood.
+//
+// window[name].subscribe(callback) calls callback with each event emitted by
+// EmitJS, as an object of the same form. It returns a function that
+// unsubscribes callback.
+func (b *jsBridge) install(ctx context.Context, send func(Msg)) {
+	ctx, b.stop = context.WithCancel(ctx)
+	queue := newMsgQueue()
+	go queue.run(ctx, send)
+
+	window := global()
+	sendFunc := b.funcOf(func(_ jsObject, args []jsObject) interface{} {
+		if len(args) == 0 {
+			return "masc: missing message"
+		}
+		msg, err := decodeBridgeMsg([]byte(args[0].String()))
+		if err != nil {
+			return "masc: " + err.Error()
+		}
+		queue.push(msg)
+		return nil
+	})
+	subscribeFunc := b.funcOf(func(_ jsObject, args []jsObject) interface{} {
+		if len(args) == 0 {
+			return nil
+		}
+		callback := args[0]
+		b.mtx.Lock()
+		b.subscribers = append(b.subscribers, callback)
+		b.mtx.Unlock()
+		return b.funcOf(func(jsObject, []jsObject) interface{} {
+			b.mtx.Lock()
+			defer b.mtx.Unlock()
+			for i, s := range b.subscribers {
+				if s.Equal(callback) {
+					b.subscribers = append(b.subscribers[:i], b.subscribers[i+1:]...)
+					break
+				}
+			}
+			return nil
+		})
+	})
+
+	bridge := window.Call("Object")
+	bridge.Set("send", sendFunc)
+	bridge.Set("subscribe", subscribeFunc)
+	window.Set(b.name, bridge)
+}
+
+func (b *jsBridge) funcOf(fn func(this jsObject, args []jsObject) interface{}) jsFunc {
+	f := funcOf(fn)
+	b.mtx.Lock()
+	b.funcs = append(b.funcs, f)
+	b.mtx.Unlock()
+	return f
+}
+
+// emit calls the subscribers with an event. It is called from the event loop.
+func (b *jsBridge) emit(msg emitJSMsg) {
+	event, err := encodeBridgeEvent(msg)
+	if err != nil {
+		global().Get("console").Call("warn", "masc: cannot emit event:", err.Error())
+		return
+	}
+	b.mtx.Lock()
+	subscribers := append([]jsObject(nil), b.subscribers...)
+	b.mtx.Unlock()
+	for _, s := range subscribers {
+		s.Call("call", nil, global().Get("JSON").Call("parse", event))
+	}
+}
+
+// uninstall removes the bridge from the window and releases its callbacks.
+func (b *jsBridge) uninstall() {
+	b.stop()
+	global().Delete(b.name)
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for _, f := range b.funcs {
+		f.Release()
+	}
+	b.funcs = nil
+	b.subscribers = nil
+}
@@ -0,0 +1,126 @@
+package masc
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type bridgeSelectMsg struct {
+	ID string `json:"id"`
+}
+
+// registerMsg registers msg for the duration of the test.
+func registerMsg(t *testing.T, name string, msg Msg) {
+	RegisterMsg(name, msg)
+	t.Cleanup(func() {
+		bridgeTypes.Lock()
+		delete(bridgeTypes.byName, name)
+		bridgeTypes.Unlock()
+	})
+}
+
+func TestDecodeBridgeMsg(t *testing.T) {
+	registerMsg(t, "select", bridgeSelectMsg{})
+	registerMsg(t, "selectPtr", &bridgeSelectMsg{})
+
+	tests := []struct {
+		json string
+		want Msg
+		err  string
+	}{
+		{json: `{"type":"select","data":{"id":"42"}}`, want: bridgeSelectMsg{ID: "42"}},
+		{json: `{"type":"select"}`, want: bridgeSelectMsg{}},
+		{json: `{"type":"selectPtr","data":{"id":"42"}}`, want: &bridgeSelectMsg{ID: "42"}},
+		{json: `{"type":"unknown"}`, err: `unknown message type "unknown"`},
+		{json: `{"type":"select","data":{"id":42}}`, err: "decoding select"},
+		{json: `not json`, err: "invalid character"},
+	}
+	for _, tst := range tests {
+		t.Run(tst.json, func(t *testing.T) {
+			got, err := decodeBridgeMsg([]byte(tst.json))
+			if tst.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tst.err) {
+					t.Fatalf("expected error containing %q, got %v", tst.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p, ok := got.(*bridgeSelectMsg); ok {
+				if *p != *tst.want.(*bridgeSelectMsg) {
+					t.Fatalf("expected %v, got %v", tst.want, got)
+				}
+				return
+			}
+			if got != tst.want {
+				t.Fatalf("expected %v, got %v", tst.want, got)
+			}
+		})
+	}
+}
+
+func TestRegisterMsgDuplicate(t *testing.T) {
+	registerMsg(t, "select", bridgeSelectMsg{})
+	got := recoverStr(func() {
+		RegisterMsg("select", bridgeSelectMsg{})
+	})
+	if want := "masc: message select is already registered"; got != want {
+		t.Fatalf("got panic %q, want %q", got, want)
+	}
+}
+
+func TestJSBridge(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+	registerMsg(t, "select", bridgeSelectMsg{})
+
+	var funcs []*jsFuncImpl
+	orig := funcOfImpl
+	funcOfImpl = func(fn func(this jsObject, args []jsObject) interface{}) jsFunc {
+		f := orig(fn).(*jsFuncImpl)
+		funcs = append(funcs, f)
+		return f
+	}
+	defer func() { funcOfImpl = orig }()
+
+	msgs := make(chan Msg, 2)
+	b := &jsBridge{name: "widget"}
+	b.install(context.Background(), func(msg Msg) { msgs <- msg })
+	send, subscribe := funcs[0], funcs[1]
+
+	// Messages are sent to the program in the order JavaScript sent them.
+	ts.strings.mock("first", `{"type":"select","data":{"id":"42"}}`)
+	ts.strings.mock("second", `{"type":"select","data":{"id":"43"}}`)
+	for _, name := range []string{"first", "second"} {
+		if err := send.goFunc(undefined(), []jsObject{&objectRecorder{ts: ts, name: name}}); err != nil {
+			t.Fatalf("expected the message to be accepted, got %v", err)
+		}
+	}
+	for _, id := range []string{"42", "43"} {
+		if msg := <-msgs; msg != (bridgeSelectMsg{ID: id}) {
+			t.Fatalf("expected select message %s, got %v", id, msg)
+		}
+	}
+
+	ts.strings.mock("unknown", `{"type":"unknown"}`)
+	err := send.goFunc(undefined(), []jsObject{&objectRecorder{ts: ts, name: "unknown"}})
+	if want := `masc: unknown message type "unknown"`; err != want {
+		t.Fatalf("expected %q, got %v", want, err)
+	}
+
+	subscribe.goFunc(undefined(), []jsObject{&objectRecorder{ts: ts, name: "callback"}})
+	b.emit(emitJSMsg{event: "selected", data: map[string]string{"id": "42"}})
+
+	unsubscribe := funcs[2]
+	unsubscribe.goFunc(undefined(), nil)
+	b.emit(emitJSMsg{event: "ignored"})
+
+	b.uninstall()
+	for _, f := range funcs {
+		if !f.released {
+			t.Fatal("expected the bridge's callbacks to be released")
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package masc
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// ResizeMsg is sent by WindowResize when the browser window is resized. Width
+// and Height are the window's inner dimensions in CSS pixels.
+type ResizeMsg struct {
+	Width, Height int
+}
+
+// VisibilityMsg is sent by VisibilityChange when the page is hidden or shown,
+// for example when the user switches tabs.
+type VisibilityMsg struct {
+	Hidden bool
+}
+
+// OnlineMsg is sent by OnlineStatus when the browser goes online or offline.
+type OnlineMsg struct {
+	Online bool
+}
+
+// PopStateMsg is sent by PopState when the user navigates the session
+// history, for example with the back button. URL is the new location.
+type PopStateMsg struct {
+	URL string
+}
+
+// HashChangeMsg is sent by HashChange when the fragment of the URL changes.
+type HashChangeMsg struct {
+	OldURL, NewURL string
+}
+
+// KeyComboMsg is sent by KeyCombos when one of its key combinations is
+// pressed. Combo is the combination as it was passed to KeyCombos.
+type KeyComboMsg struct {
+	Combo string
+}
+
+// globalEventKey identifies the subscriptions in this file, so they cannot
+// clash with subscription keys chosen by applications.
+type globalEventKey string
+
+// WindowResize returns a subscription that sends a ResizeMsg whenever the
+// window is resized.
+func WindowResize() Sub {
+	return Sub{
+		Key: globalEventKey("resize"),
+		Run: func(ctx context.Context, send func(Msg)) {
+			window := global()
+			listen(ctx, window, send, func(jsObject) Msg {
+				return ResizeMsg{
+					Width:  window.Get("innerWidth").Int(),
+					Height: window.Get("innerHeight").Int(),
+				}
+			}, "resize")
+		},
+	}
+}
+
+// VisibilityChange returns a subscription that sends a VisibilityMsg whenever
+// the page's visibility changes.
+func VisibilityChange() Sub {
+	return Sub{
+		Key: globalEventKey("visibilitychange"),
+		Run: func(ctx context.Context, send func(Msg)) {
+			document := global().Get("document")
+			listen(ctx, document, send, func(jsObject) Msg {
+				return VisibilityMsg{Hidden: document.Get("visibilityState").String() == "hidden"}
+			}, "visibilitychange")
+		},
+	}
+}
+
+// OnlineStatus returns a subscription that sends an OnlineMsg whenever the
+// browser goes online or offline.
+func OnlineStatus() Sub {
+	return Sub{
+		Key: globalEventKey("online"),
+		Run: func(ctx context.Context, send func(Msg)) {
+			listen(ctx, global(), send, func(event jsObject) Msg {
+				return OnlineMsg{Online: event.Get("type").String() == "online"}
+			}, "online", "offline")
+		},
+	}
+}
+
+// PopState returns a subscription that sends a PopStateMsg whenever the
+// active history entry changes.
+func PopState() Sub {
+	return Sub{
+		Key: globalEventKey("popstate"),
+		Run: func(ctx context.Context, send func(Msg)) {
+			window := global()
+			listen(ctx, window, send, func(jsObject) Msg {
+				return PopStateMsg{URL: window.Get("location").Get("href").String()}
+			}, "popstate")
+		},
+	}
+}
+
+// HashChange returns a subscription that sends a HashChangeMsg whenever the
+// fragment of the URL changes.
+func HashChange() Sub {
+	return Sub{
+		Key: globalEventKey("hashchange"),
+		Run: func(ctx context.Context, send func(Msg)) {
+			window := global()
+			listen(ctx, window, send, func(event jsObject) Msg {
+				return HashChangeMsg{
+					OldURL: event.Get("oldURL").String(),
+					NewURL: event.Get("newURL").String(),
+				}
+			}, "hashchange")
+		},
+	}
+}
+
+// KeyCombos returns a subscription that sends a KeyComboMsg when one of the
+// given key combinations is pressed anywhere in the document. The browser's
+// default action for a matched combination, such as saving the page for
+// "ctrl+s", is prevented.
+//
+// A combination is a key, as reported by KeyboardEvent.key, optionally
+// preceded by modifiers, all separated by "+": for example "escape", "ctrl+s"
+// or "ctrl+shift+z". The modifiers are ctrl, alt, shift and meta. Keys and
+// modifiers are case-insensitive; use "plus" for the + key.
+//
+// Example:
+//
+//	func (m model) Subscriptions() []masc.Sub {
+//	    return []masc.Sub{masc.KeyCombos("ctrl+s", "escape")}
+//	}
+func KeyCombos(combos ...string) Sub {
+	parsed := make([]keyCombo, len(combos))
+	for i, combo := range combos {
+		parsed[i] = parseKeyCombo(combo)
+	}
+	return Sub{
+		Key: globalEventKey("keydown:" + strings.Join(combos, ",")),
+		Run: func(ctx context.Context, send func(Msg)) {
+			listen(ctx, global().Get("document"), send, func(event jsObject) Msg {
+				pressed := keyCombo{
+					key:   strings.ToLower(event.Get("key").String()),
+					ctrl:  event.Get("ctrlKey").Bool(),
+					alt:   event.Get("altKey").Bool(),
+					shift: event.Get("shiftKey").Bool(),
+					meta:  event.Get("metaKey").Bool(),
+				}
+				for i, combo := range parsed {
+					if combo == pressed {
+						event.Call("preventDefault")
+						return KeyComboMsg{Combo: combos[i]}
+					}
+				}
+				return nil
+			}, "keydown")
+		},
+	}
+}
+
+// keyCombo is a parsed key combination.
+type keyCombo struct {
+	key                    string
+	ctrl, alt, shift, meta bool
+}
+
+func parseKeyCombo(combo string) keyCombo {
+	var c keyCombo
+	parts := strings.Split(strings.ToLower(combo), "+")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if i == len(parts)-1 {
+			if part == "plus" {
+				part = "+"
+			}
+			c.key = part
+			continue
+		}
+		switch part {
+		case "ctrl", "control":
+			c.ctrl = true
+		case "alt", "option":
+			c.alt = true
+		case "shift":
+			c.shift = true
+		case "meta", "cmd", "command":
+			c.meta = true
+		default:
+			panic("masc: unknown modifier " + part + " in key combination " + combo)
+		}
+	}
+	return c
+}
+
+// listen adds an event listener for each event type to target, sending the
+// message returned by fn for each event, in order, until ctx is cancelled. It
+// then removes the listeners and releases the callback.
+func listen(ctx context.Context, target jsObject, send func(Msg), fn func(event jsObject) Msg, eventTypes ...string) {
+	// Don't block the browser's event loop on the program: the listener
+	// queues the messages, and this goroutine sends them.
+	queue := newMsgQueue()
+	cb := funcOf(func(_ jsObject, args []jsObject) interface{} {
+		if msg := fn(args[0]); msg != nil {
+			queue.push(msg)
+		}
+		return undefined()
+	})
+	for _, eventType := range eventTypes {
+		target.Call("addEventListener", eventType, cb)
+	}
+	defer func() {
+		for _, eventType := range eventTypes {
+			target.Call("removeEventListener", eventType, cb)
+		}
+		cb.Release()
+	}()
+	queue.run(ctx, send)
+}
+
+// msgQueue passes the messages of JavaScript callbacks to a program in order,
+// without blocking the browser's event loop on the program.
+type msgQueue struct {
+	mtx    sync.Mutex
+	queued []Msg
+	ready  chan struct{}
+}
+
+func newMsgQueue() *msgQueue {
+	return &msgQueue{ready: make(chan struct{}, 1)}
+}
+
+// push queues msg to be sent by run. It does not block.
+func (q *msgQueue) push(msg Msg) {
+	q.mtx.Lock()
+	q.queued = append(q.queued, msg)
+	q.mtx.Unlock()
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the queued messages, in the order they were pushed, until ctx is
+// done.
+func (q *msgQueue) run(ctx context.Context, send func(Msg)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.ready:
+			q.mtx.Lock()
+			msgs := q.queued
+			q.queued = nil
+			q.mtx.Unlock()
+			for _, msg := range msgs {
+				if ctx.Err() != nil {
+					return
+				}
+				send(msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package masc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the source of time used by time-based commands and subscriptions
+// such as Tick, Every, Interval and Yield. Programs use the system clock
+// unless another one is set with WithClock or UseClock, which lets tests
+// control time instead of waiting for it.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer creates a Timer that sends the current time on its channel
+	// after at least duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event created by a Clock, like time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+
+	// Stop prevents the Timer from firing. It returns false if the timer
+	// has already fired or been stopped.
+	Stop() bool
+}
+
+// systemClock implements Clock with the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+type systemTimer struct{ t *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.t.C }
+func (t systemTimer) Stop() bool          { return t.t.Stop() }
+
+// defaultClock holds the clock set by UseClock, as a clockValue.
+var defaultClock atomic.Value
+
+type clockValue struct{ Clock }
+
+// UseClock sets the Clock of Tick, Every and Yield, which are not passed a
+// context, and of the programs and contexts without a clock of their own, see WithClock and
+// ContextWithClock. A nil clock restores the system clock.
+//
+// Unlike WithClock, UseClock affects every program, so it is mostly useful in
+// tests of a single program.
+func UseClock(clock Clock) {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	defaultClock.Store(clockValue{clock})
+}
+
+// useClock returns the clock set by UseClock, or the system clock.
+func useClock() Clock {
+	if v, ok := defaultClock.Load().(clockValue); ok {
+		return v.Clock
+	}
+	return systemClock{}
+}
+
+// clockKey is the context key under which a Clock is stored.
+type clockKey struct{}
+
+// ContextWithClock returns a copy of ctx carrying clock. Programs attach their
+// clock to the contexts passed to CmdCtx commands and subscriptions.
+func ContextWithClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// ClockFromContext returns the Clock carried by ctx, or the clock set by
+// UseClock if it carries none.
+//
+// Example:
+//
+//	func poll(ctx context.Context) masc.Msg {
+//	    clock := masc.ClockFromContext(ctx)
+//	    start := clock.Now()
+//	    ...
+//	    return polledMsg{elapsed: clock.Now().Sub(start)}
+//	}
+func ClockFromContext(ctx context.Context) Clock {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return clock
+	}
+	return useClock()
+}
+
+// sleep pauses for d on clock, returning early with false if ctx is done.
+func sleep(ctx context.Context, clock Clock, d time.Duration) (time.Time, bool) {
+	// A command waiting on the clock gives up its place within the
+	// concurrency limit, see WithCommandConcurrency.
+	sl := slotFromContext(ctx)
+	sl.release()
+	defer sl.acquire()
+	t := clock.NewTimer(d)
+	select {
+	case now := <-t.C():
+		return now, true
+	case <-ctx.Done():
+		t.Stop()
+		return time.Time{}, false
+	}
+}
@@ -26,6 +26,7 @@ var (
 	serveDir        string
 	currentBuildDir string
 	buildMutex      sync.RWMutex
+	reloads         = newReloadBroadcaster()
 )
 
 // indexHTML is the HTML template served for the Thunder app root.
@@ -40,6 +41,19 @@ const indexHTML = `<!DOCTYPE html>
         WebAssembly.instantiateStreaming(fetch("bundle.wasm"), go.importObject).then((result) => {
             go.run(result.instance);
         });
+
+        // Reload the page when masc serve has rebuilt the app, keeping the
+        // state of models that implement masc.Snapshotter.
+        const reload = new EventSource("/masc/reload");
+        reload.addEventListener("reload", () => {
+            if (typeof window.__mascSnapshot === "function") {
+                const snapshot = window.__mascSnapshot();
+                if (snapshot) {
+                    sessionStorage.setItem("masc:snapshot", snapshot);
+                }
+            }
+            location.reload();
+        });
     </script>
 </head>
 <body>
@@ -396,6 +410,7 @@ func watchAndRebuild(appDir string) {
 			fmt.Fprintf(os.Stderr, "Warning: failed to remove old build directory: %v\n", err)
 		}
 		fmt.Println("Rebuild complete")
+		reloads.notify()
 		return nil
 	})
 	if err != nil {
@@ -419,6 +434,79 @@ func wasmExecHandler(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, filepath.Join(dirPath, "wasm_exec.js"))
 }
 
+// reloadBroadcaster tells the pages connected to the reload endpoint that the
+// app has been rebuilt.
+type reloadBroadcaster struct {
+	mtx     sync.Mutex
+	clients map[chan struct{}]struct{}
+}
+
+func newReloadBroadcaster() *reloadBroadcaster {
+	return &reloadBroadcaster{clients: make(map[chan struct{}]struct{})}
+}
+
+// subscribe registers a client. The returned channel receives a value after
+// each rebuild until the client is unsubscribed.
+func (b *reloadBroadcaster) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	b.mtx.Lock()
+	b.clients[ch] = struct{}{}
+	b.mtx.Unlock()
+	return ch
+}
+
+func (b *reloadBroadcaster) unsubscribe(ch chan struct{}) {
+	b.mtx.Lock()
+	delete(b.clients, ch)
+	b.mtx.Unlock()
+}
+
+// notify signals every client, without waiting for clients that have not yet
+// handled the previous rebuild.
+func (b *reloadBroadcaster) notify() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for ch := range b.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// reloadHandler streams a server-sent "reload" event to the page after each
+// rebuild.
+func reloadHandler(w http.ResponseWriter, r *http.Request) {
+	// The stream outlives the server's write timeout.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ch := reloads.subscribe()
+	defer reloads.unsubscribe(ch)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			if _, err := io.WriteString(w, "event: reload\ndata: {}\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
 // indexHandler serves the indexHTML template directly.
 func indexHandler(w http.ResponseWriter, r *http.Request) {
 	// Only serve index for root path and paths that don't match other handlers
@@ -487,6 +575,7 @@ func runServe(cmd *cobra.Command, args []string) error {
 	// Set up HTTP handlers
 	http.HandleFunc("/bundle.wasm", wasmHandler)
 	http.HandleFunc("/wasm_exec.js", wasmExecHandler)
+	http.HandleFunc("/masc/reload", reloadHandler)
 	http.HandleFunc("/", indexHandler)
 
 	// Start the server in a goroutine so we can open browser after it starts
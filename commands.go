@@ -1,7 +1,13 @@
 package masc
 
 import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
 	"time"
+
+	"github.com/octoberswimmer/masc/internal/cmds"
 )
 
 // Batch performs a bunch of commands concurrently with no ordering guarantees
@@ -43,6 +49,432 @@ func Sequence(cmds ...Cmd) Cmd {
 // sequenceMsg is used internally to run the given commands in order.
 type sequenceMsg []Cmd
 
+// CmdCtx is a context-aware IO operation that returns a message when it's
+// complete. The context it receives is a child of the program's context and is
+// cancelled when the program is killed or quits, so long-running work such as
+// HTTP requests or polling loops can stop instead of leaking.
+//
+// A CmdCtx is turned into a Cmd with ContextCmd.
+type CmdCtx func(context.Context) Msg
+
+// ContextCmd converts a CmdCtx into a Cmd that can be returned from Init or
+// Update, or combined with Batch and Sequence.
+//
+// Example:
+//
+//	func fetchUser(id string) masc.Cmd {
+//	    return masc.ContextCmd(func(ctx context.Context) masc.Msg {
+//	        req, _ := http.NewRequestWithContext(ctx, "GET", "/users/"+id, nil)
+//	        resp, err := http.DefaultClient.Do(req)
+//	        if err != nil {
+//	            return errMsg{err}
+//	        }
+//	        return userMsg{resp}
+//	    })
+//	}
+func ContextCmd(cmd CmdCtx) Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() Msg {
+		return contextCmdMsg(cmd)
+	}
+}
+
+// contextCmdMsg is used internally to run a CmdCtx with a context derived from
+// the program's context.
+type contextCmdMsg CmdCtx
+
+// Let masctest run the commands of these internal messages.
+func init() {
+	cmds.Sequence = func(msg interface{}) ([]interface{}, bool) {
+		seq, ok := msg.(sequenceMsg)
+		if !ok {
+			return nil, false
+		}
+		list := make([]interface{}, len(seq))
+		for i, cmd := range seq {
+			list[i] = cmd
+		}
+		return list, true
+	}
+	cmds.Context = func(msg interface{}) (func(context.Context) interface{}, bool) {
+		cmd, ok := msg.(contextCmdMsg)
+		if !ok {
+			return nil, false
+		}
+		return func(ctx context.Context) interface{} { return cmd(ctx) }, true
+	}
+}
+
+// Priority orders commands waiting to run when the program limits how many
+// commands run at once, see WithCommandConcurrency. Commands with a higher
+// priority run first; commands of equal priority run in the order they were
+// issued.
+type Priority int
+
+// Command priorities. Commands run with PriorityNormal unless they are wrapped
+// with Prioritize.
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+// Prioritize runs cmd with the given priority. Commands produced by cmd, such
+// as the commands of a Batch it returns, inherit the priority.
+//
+// Example:
+//
+//	return m, masc.Batch(
+//	    masc.Prioritize(masc.PriorityHigh, save(m.doc)),
+//	    masc.Prioritize(masc.PriorityLow, prefetch(m.next)),
+//	)
+func Prioritize(priority Priority, cmd Cmd) Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() Msg {
+		return PriorityMsg{Priority: priority, Cmd: cmd}
+	}
+}
+
+// PriorityMsg is a message used to run a command with a priority. You can send
+// a PriorityMsg with Prioritize.
+type PriorityMsg struct {
+	Priority Priority
+	Cmd      Cmd
+}
+
+// Replace runs cmd, cancelling any command started earlier with the same key
+// that has not finished yet. The superseded command's context is cancelled and
+// its result is discarded, so only the latest command for a key delivers a
+// message. Keys are compared with ==, like map keys.
+//
+// Example:
+//
+//	case queryChangedMsg:
+//	    return m, masc.Replace("search", search(string(msg)))
+func Replace(key interface{}, cmd Cmd) Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return ContextCmd(func(ctx context.Context) Msg {
+		ctx, release := keyedCmdsFromContext(ctx).claim(ctx, key)
+		defer release()
+		return runWithContext(ctx, cmd)
+	})
+}
+
+// Debounce runs cmd once d has passed without another command with the same
+// key being started. Each call supersedes the previous one for the key, like
+// Replace, so a burst of calls results in a single run of the last cmd.
+//
+// Example:
+//
+//	case inputMsg:
+//	    m.draft = string(msg)
+//	    return m, masc.Debounce("autosave", time.Second, save(m.draft))
+func Debounce(key interface{}, d time.Duration, cmd Cmd) Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return ContextCmd(func(ctx context.Context) Msg {
+		ctx, release := keyedCmdsFromContext(ctx).claim(ctx, key)
+		defer release()
+		if _, ok := sleep(ctx, ClockFromContext(ctx), d); !ok {
+			return nil
+		}
+		return runWithContext(ctx, cmd)
+	})
+}
+
+// Throttle runs cmd at most once every d for the key. A call made while the
+// key is throttled waits until d has passed since the previous run and
+// supersedes any other call waiting for the same key, so the last cmd of a
+// burst always runs.
+func Throttle(key interface{}, d time.Duration, cmd Cmd) Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return ContextCmd(func(ctx context.Context) Msg {
+		keyed := keyedCmdsFromContext(ctx)
+		clock := ClockFromContext(ctx)
+		wait := keyed.throttle(key, d, clock.Now())
+		if wait <= 0 {
+			return runWithContext(ctx, cmd)
+		}
+		ctx, release := keyed.claim(ctx, key)
+		defer release()
+		if _, ok := sleep(ctx, clock, wait); !ok {
+			return nil
+		}
+		if keyed.throttle(key, d, clock.Now()) > 0 {
+			// Another run took the slot while this one waited.
+			return nil
+		}
+		return runWithContext(ctx, cmd)
+	})
+}
+
+// RetryPolicy configures Retry.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times the command is run,
+	// including the first attempt. Zero or less means 3.
+	MaxAttempts int
+
+	// Delay is the pause before the first retry. Zero means 100ms.
+	Delay time.Duration
+
+	// MaxDelay caps the pause between attempts. Zero means no cap.
+	MaxDelay time.Duration
+
+	// Multiplier grows the pause after each retry. Values below 1 mean 2,
+	// doubling the pause every time.
+	Multiplier float64
+
+	// Jitter randomizes each pause by up to this fraction of it, in either
+	// direction, so that clients do not retry in lockstep. It is clamped to
+	// [0, 1].
+	Jitter float64
+
+	// Failed reports whether the message returned by an attempt is a failure
+	// that should be retried. If nil, messages that are errors are retried.
+	Failed func(Msg) bool
+}
+
+// backoff returns the pause after the given failed attempt, counting from 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.Delay)
+	if d == 0 {
+		d = float64(100 * time.Millisecond)
+	}
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 2
+	}
+	d *= math.Pow(mult, float64(attempt-1))
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	if jitter := math.Max(0, math.Min(1, p.Jitter)); jitter > 0 {
+		d += d * jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// Retry runs cmd until it returns a message the policy does not consider
+// failed, or the policy runs out of attempts, pausing with exponential backoff
+// between attempts. The message of the last attempt is returned. If cmd is a
+// ContextCmd it receives the program's context, and no further attempts are
+// made once the program exits.
+//
+// Retry judges a single message, so cmd should not be a Batch or Sequence;
+// Retry commands can be used inside them instead.
+//
+// Example:
+//
+//	masc.Retry(fetchUser(id), masc.RetryPolicy{
+//	    MaxAttempts: 5,
+//	    Delay:       time.Second,
+//	    Jitter:      0.2,
+//	})
+func Retry(cmd Cmd, policy RetryPolicy) Cmd {
+	if cmd == nil {
+		return nil
+	}
+	attempts := policy.MaxAttempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	failed := policy.Failed
+	if failed == nil {
+		failed = func(msg Msg) bool {
+			_, ok := msg.(error)
+			return ok
+		}
+	}
+	return ContextCmd(func(ctx context.Context) Msg {
+		clock := ClockFromContext(ctx)
+		for attempt := 1; ; attempt++ {
+			msg := runWithContext(ctx, cmd)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if attempt >= attempts || !failed(msg) {
+				return msg
+			}
+			if _, ok := sleep(ctx, clock, policy.backoff(attempt)); !ok {
+				return nil
+			}
+		}
+	})
+}
+
+// Timeout runs cmd and returns its message, or onTimeout if cmd takes longer
+// than d. If cmd is a ContextCmd, its context is cancelled when the timeout
+// elapses. A plain Cmd cannot be cancelled: it keeps running in the background
+// after the timeout, and its eventual result is discarded.
+//
+// The time cmd spends waiting on the program's Clock, such as with
+// TickContext, is measured on that Clock, so that it times out with a fake
+// Clock as it would with the system clock. Other work is timed in real time.
+//
+// Example:
+//
+//	masc.Timeout(5*time.Second, fetchUser(id), errMsg{errors.New("request timed out")})
+func Timeout(d time.Duration, cmd Cmd, onTimeout Msg) Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return ContextCmd(func(parent context.Context) Msg {
+		ctx, cancel := context.WithTimeout(parent, d)
+		defer cancel()
+		clock := &deadlineClock{Clock: ClockFromContext(parent), expire: cancel}
+		clock.deadline = clock.Now().Add(d)
+		ctx = ContextWithClock(ctx, clock)
+
+		done := make(chan Msg, 1)
+		go func() {
+			done <- runWithContext(ctx, cmd)
+		}()
+		var msg Msg
+		select {
+		case msg = <-done:
+		case <-ctx.Done():
+		}
+		switch {
+		case parent.Err() != nil:
+			return nil
+		case ctx.Err() != nil:
+			return onTimeout
+		}
+		return msg
+	})
+}
+
+// deadlineClock is the Clock of a command run by Timeout. A timer that would
+// fire after the deadline calls expire at the deadline instead of firing.
+type deadlineClock struct {
+	Clock
+	deadline time.Time
+	expire   func()
+}
+
+func (c *deadlineClock) NewTimer(d time.Duration) Timer {
+	left := c.deadline.Sub(c.Now())
+	if d <= left {
+		return c.Clock.NewTimer(d)
+	}
+	t := &deadlineTimer{Timer: c.Clock.NewTimer(left), stop: make(chan struct{})}
+	go func() {
+		select {
+		case <-t.Timer.C():
+			c.expire()
+		case <-t.stop:
+		}
+	}()
+	return t
+}
+
+// deadlineTimer is a timer of a deadlineClock that never fires.
+type deadlineTimer struct {
+	Timer
+	stop chan struct{}
+	once sync.Once
+}
+
+func (t *deadlineTimer) C() <-chan time.Time { return nil }
+
+func (t *deadlineTimer) Stop() bool {
+	t.once.Do(func() { close(t.stop) })
+	return t.Timer.Stop()
+}
+
+// runWithContext runs cmd with ctx if it is context-aware, and discards its
+// result if ctx was cancelled in the meantime.
+func runWithContext(ctx context.Context, cmd Cmd) Msg {
+	msg := cmd()
+	if c, ok := msg.(contextCmdMsg); ok {
+		msg = c(ctx)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return msg
+}
+
+// keyedCmds tracks the commands started by Replace, Debounce and Throttle for
+// a program, so that commands can supersede earlier ones with the same key.
+type keyedCmds struct {
+	mtx     sync.Mutex
+	pending map[interface{}]*keyedCmd
+	// ran holds the time each throttled key last ran.
+	ran map[interface{}]time.Time
+}
+
+type keyedCmd struct {
+	cancel context.CancelFunc
+}
+
+func newKeyedCmds() *keyedCmds {
+	return &keyedCmds{
+		pending: make(map[interface{}]*keyedCmd),
+		ran:     make(map[interface{}]time.Time),
+	}
+}
+
+// keyedCmdsKey is the context key under which a program's keyedCmds is stored.
+type keyedCmdsKey struct{}
+
+// keyedCmdsFromContext returns the keyedCmds of the program that ctx belongs
+// to, or nil when a command is run outside a program.
+func keyedCmdsFromContext(ctx context.Context) *keyedCmds {
+	keyed, _ := ctx.Value(keyedCmdsKey{}).(*keyedCmds)
+	return keyed
+}
+
+// claim cancels the pending command for key and registers a new one, returning
+// its context. The returned function must be called when the command is done.
+func (k *keyedCmds) claim(ctx context.Context, key interface{}) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if k == nil {
+		return ctx, cancel
+	}
+	c := &keyedCmd{cancel: cancel}
+	k.mtx.Lock()
+	if prev, ok := k.pending[key]; ok {
+		prev.cancel()
+	}
+	k.pending[key] = c
+	k.mtx.Unlock()
+	return ctx, func() {
+		k.mtx.Lock()
+		if k.pending[key] == c {
+			delete(k.pending, key)
+		}
+		k.mtx.Unlock()
+		cancel()
+	}
+}
+
+// throttle returns how long a run of key must wait so that runs are at least d
+// apart. If it need not wait, the run is recorded at now.
+func (k *keyedCmds) throttle(key interface{}, d time.Duration, now time.Time) time.Duration {
+	if k == nil {
+		return 0
+	}
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+	if last, ok := k.ran[key]; ok {
+		if wait := last.Add(d).Sub(now); wait > 0 {
+			return wait
+		}
+	}
+	k.ran[key] = now
+	return 0
+}
+
 // Every is a command that ticks in sync with the system clock. So, if you
 // wanted to tick with the system clock every second, minute or hour you
 // could use this. It's also handy for having different things tick in sync.
@@ -88,16 +520,38 @@ type sequenceMsg []Cmd
 //	    return m, nil
 //	}
 //
+// Alternatively, have your model implement Subscriber and return an Interval
+// subscription for as long as it wants to receive ticks.
+//
+// Every waits on the clock set with UseClock, the system clock by default. Use
+// EveryContext to wait on the program's Clock instead.
+//
 // Every is analogous to Tick in the Elm Architecture.
 func Every(duration time.Duration, fn func(time.Time) Msg) Cmd {
 	return func() Msg {
-		n := time.Now()
+		clock := useClock()
+		n := clock.Now()
 		d := n.Truncate(duration).Add(duration).Sub(n)
-		t := time.NewTimer(d)
-		return fn(<-t.C)
+		t := clock.NewTimer(d)
+		return fn(<-t.C())
 	}
 }
 
+// EveryContext is like Every, but waits on the program's Clock, see
+// WithClock, and sends no message if the program exits before the tick.
+func EveryContext(duration time.Duration, fn func(time.Time) Msg) Cmd {
+	return ContextCmd(func(ctx context.Context) Msg {
+		clock := ClockFromContext(ctx)
+		n := clock.Now()
+		d := n.Truncate(duration).Add(duration).Sub(n)
+		t, ok := sleep(ctx, clock, d)
+		if !ok {
+			return nil
+		}
+		return fn(t)
+	})
+}
+
 // Tick produces a command at an interval independent of the system clock at
 // the given duration. That is, the timer begins precisely when invoked,
 // and runs for its entire duration.
@@ -136,13 +590,28 @@ func Every(duration time.Duration, fn func(time.Time) Msg) Cmd {
 //	    }
 //	    return m, nil
 //	}
+//
+// Tick waits on the clock set with UseClock, the system clock by default. Use
+// TickContext to wait on the program's Clock instead.
 func Tick(d time.Duration, fn func(time.Time) Msg) Cmd {
 	return func() Msg {
-		t := time.NewTimer(d)
-		return fn(<-t.C)
+		t := useClock().NewTimer(d)
+		return fn(<-t.C())
 	}
 }
 
+// TickContext is like Tick, but waits on the program's Clock, see WithClock,
+// and sends no message if the program exits before the tick.
+func TickContext(d time.Duration, fn func(time.Time) Msg) Cmd {
+	return ContextCmd(func(ctx context.Context) Msg {
+		t, ok := sleep(ctx, ClockFromContext(ctx), d)
+		if !ok {
+			return nil
+		}
+		return fn(t)
+	})
+}
+
 // Sequentially produces a command that sequentially executes the given
 // commands.
 // The Msg returned is the first non-nil message returned by a Cmd.
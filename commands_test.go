@@ -1,7 +1,10 @@
 package masc
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -26,6 +29,28 @@ func TestTick(t *testing.T) {
 	}
 }
 
+func TestTickContext(t *testing.T) {
+	expected := "tick"
+	cmd := TickContext(time.Millisecond, func(t time.Time) Msg {
+		return expected
+	})()
+	msg := cmd.(contextCmdMsg)(context.Background())
+	if expected != msg {
+		t.Fatalf("expected a msg %v but got %v", expected, msg)
+	}
+}
+
+func TestTickCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cmd := TickContext(time.Hour, func(t time.Time) Msg {
+		return "tick"
+	})()
+	if msg := cmd.(contextCmdMsg)(ctx); msg != nil {
+		t.Fatalf("expected no msg from a cancelled tick, got %v", msg)
+	}
+}
+
 func TestSequentially(t *testing.T) {
 	expectedErrMsg := fmt.Errorf("some err")
 	expectedStrMsg := "some msg"
@@ -105,3 +130,282 @@ func TestBatch(t *testing.T) {
 		}
 	})
 }
+
+func TestContextCmd(t *testing.T) {
+	t.Run("nil cmd", func(t *testing.T) {
+		if c := ContextCmd(nil); c != nil {
+			t.Fatalf("expected nil, got %+v", c)
+		}
+	})
+	t.Run("wraps cmd", func(t *testing.T) {
+		msg := ContextCmd(func(context.Context) Msg { return "ctx" })()
+		cmd, ok := msg.(contextCmdMsg)
+		if !ok {
+			t.Fatalf("expected a contextCmdMsg, got %T", msg)
+		}
+		if got := cmd(context.Background()); got != "ctx" {
+			t.Fatalf("expected a msg %v but got %v", "ctx", got)
+		}
+	})
+}
+
+// fakeClock is a Clock whose timers fire only when the test advances it.
+type fakeClock struct {
+	mtx     sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	created chan struct{}
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0), created: make(chan struct{}, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mtx.Lock()
+	t := &fakeTimer{clock: c, when: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	c.mtx.Unlock()
+	c.created <- struct{}{}
+	return t
+}
+
+// advance moves the clock forward by d, firing the timers that are due.
+func (c *fakeClock) advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.when.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = pending
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	when  time.Time
+	c     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for i, p := range c.timers {
+		if p == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// runKeyedCmds runs cmds concurrently, in order, with a shared program context
+// and a fake clock. Each command is started once the previous one has returned
+// or is waiting on the clock. The clock is then advanced by d and the results
+// are returned in the same order.
+func runKeyedCmds(d time.Duration, cmds ...Cmd) []Msg {
+	clock := newFakeClock()
+	ctx := context.WithValue(context.Background(), keyedCmdsKey{}, newKeyedCmds())
+	ctx = ContextWithClock(ctx, clock)
+	results := make([]chan Msg, len(cmds))
+	for i, cmd := range cmds {
+		results[i] = make(chan Msg, 1)
+		go func(cmd Cmd, result chan<- Msg) {
+			result <- cmd().(contextCmdMsg)(ctx)
+		}(cmd, results[i])
+		select {
+		case msg := <-results[i]:
+			results[i] <- msg
+		case <-clock.created:
+		}
+	}
+	clock.advance(d)
+	msgs := make([]Msg, len(cmds))
+	for i, result := range results {
+		msgs[i] = <-result
+	}
+	return msgs
+}
+
+func TestReplace(t *testing.T) {
+	ctx := context.WithValue(context.Background(), keyedCmdsKey{}, newKeyedCmds())
+	started := make(chan struct{})
+	slow := ContextCmd(func(ctx context.Context) Msg {
+		close(started)
+		<-ctx.Done()
+		return "slow"
+	})
+	fast := func() Msg { return "fast" }
+
+	superseded := make(chan Msg)
+	go func() {
+		superseded <- Replace("k", slow)().(contextCmdMsg)(ctx)
+	}()
+	<-started
+	latest := Replace("k", fast)().(contextCmdMsg)(ctx)
+	if got := fmt.Sprint([]Msg{<-superseded, latest}); got != "[<nil> fast]" {
+		t.Fatalf("expected only the latest result, got %s", got)
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	var runs int32
+	cmd := func(msg string) Cmd {
+		return func() Msg {
+			atomic.AddInt32(&runs, 1)
+			return msg
+		}
+	}
+	msgs := runKeyedCmds(20*time.Millisecond,
+		Debounce("k", 20*time.Millisecond, cmd("a")),
+		Debounce("k", 20*time.Millisecond, cmd("b")),
+		Debounce("other", 20*time.Millisecond, cmd("c")),
+	)
+	if got := fmt.Sprint(msgs); got != "[<nil> b c]" {
+		t.Fatalf("unexpected debounced results %s", got)
+	}
+	if runs != 2 {
+		t.Fatalf("expected 2 commands to run, got %d", runs)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	cmd := func(msg string) Cmd {
+		return func() Msg { return msg }
+	}
+	msgs := runKeyedCmds(50*time.Millisecond,
+		Throttle("k", 50*time.Millisecond, cmd("a")),
+		Throttle("k", 50*time.Millisecond, cmd("b")),
+		Throttle("k", 50*time.Millisecond, cmd("c")),
+	)
+	if got := fmt.Sprint(msgs); got != "[a <nil> c]" {
+		t.Fatalf("unexpected throttled results %s", got)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var attempts int
+	flaky := func() Msg {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("attempt %d failed", attempts)
+		}
+		return "ok"
+	}
+	msg := Retry(flaky, RetryPolicy{Delay: time.Millisecond})().(contextCmdMsg)(context.Background())
+	if msg != "ok" || attempts != 3 {
+		t.Fatalf("expected success on attempt 3, got %v after %d attempts", msg, attempts)
+	}
+
+	attempts = 0
+	msg = Retry(flaky, RetryPolicy{MaxAttempts: 2, Delay: time.Millisecond})().(contextCmdMsg)(context.Background())
+	if err, ok := msg.(error); !ok || err.Error() != "attempt 2 failed" || attempts != 2 {
+		t.Fatalf("expected the last failure after 2 attempts, got %v after %d attempts", msg, attempts)
+	}
+
+	attempts = 0
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		Delay:       time.Millisecond,
+		Failed:      func(msg Msg) bool { return msg != "ok" },
+	}
+	msg = Retry(func() Msg { attempts++; return "nope" }, policy)().(contextCmdMsg)(context.Background())
+	if msg != "nope" || attempts != 5 {
+		t.Fatalf("expected the predicate to drive retries, got %v after %d attempts", msg, attempts)
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var attempts int
+	failing := func() Msg {
+		attempts++
+		cancel()
+		return fmt.Errorf("failed")
+	}
+	if msg := Retry(failing, RetryPolicy{})().(contextCmdMsg)(ctx); msg != nil || attempts != 1 {
+		t.Fatalf("expected no retries after cancellation, got %v after %d attempts", msg, attempts)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{Delay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	var got []time.Duration
+	for attempt := 1; attempt <= 4; attempt++ {
+		got = append(got, p.backoff(attempt))
+	}
+	if fmt.Sprint(got) != "[10ms 20ms 40ms 50ms]" {
+		t.Fatalf("unexpected backoff %v", got)
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.backoff(1); d < 5*time.Millisecond || d > 15*time.Millisecond {
+			t.Fatalf("jittered backoff %v out of range", d)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	fast := func() Msg { return "fast" }
+	if msg := Timeout(time.Second, fast, "timeout")().(contextCmdMsg)(context.Background()); msg != "fast" {
+		t.Fatalf("expected the command's message, got %v", msg)
+	}
+
+	var cancelled atomic.Value
+	slow := ContextCmd(func(ctx context.Context) Msg {
+		<-ctx.Done()
+		cancelled.Store(true)
+		return "slow"
+	})
+	if msg := Timeout(time.Millisecond, slow, "timeout")().(contextCmdMsg)(context.Background()); msg != "timeout" {
+		t.Fatalf("expected the timeout message, got %v", msg)
+	}
+	for i := 0; cancelled.Load() == nil; i++ {
+		if i > 100 {
+			t.Fatal("expected the timed out command to be cancelled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestTimeoutClock tests that the time a command waits on the clock counts
+// towards its timeout on that clock.
+func TestTimeoutClock(t *testing.T) {
+	tick := func(d time.Duration) Cmd {
+		return TickContext(d, func(time.Time) Msg { return "tick" })
+	}
+	for _, tt := range []struct {
+		tick time.Duration
+		want Msg
+	}{
+		{tick: time.Second, want: "tick"},
+		{tick: 2 * time.Second, want: "timeout"},
+	} {
+		clock := newFakeClock()
+		ctx := ContextWithClock(context.Background(), clock)
+		result := make(chan Msg, 1)
+		go func() {
+			result <- Timeout(time.Second, tick(tt.tick), "timeout")().(contextCmdMsg)(ctx)
+		}()
+		<-clock.created
+		clock.advance(time.Second)
+		if msg := <-result; msg != tt.want {
+			t.Errorf("Timeout of a %v tick: got %v, want %v", tt.tick, msg, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package masc
+
+import (
+	"context"
+	"fmt"
+)
+
+// MapCmd returns a command that runs cmd and wraps its message with f, in the
+// style of Elm's Cmd.map. It lets a parent model return the commands of a
+// child model, with their messages tagged so that they can be routed back to
+// the child.
+//
+// The commands of batches, sequences and prioritized commands are mapped one
+// by one. Messages meant for the program rather than the model, such as the
+// one returned by Quit, are not wrapped.
+func MapCmd(cmd Cmd, f func(Msg) Msg) Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() Msg {
+		return mapMsg(cmd(), f)
+	}
+}
+
+// mapMsg wraps msg, the message of a command, with f.
+func mapMsg(msg Msg, f func(Msg) Msg) Msg {
+	switch msg := msg.(type) {
+	case nil:
+		return nil
+	case BatchMsg:
+		mapped := make(BatchMsg, len(msg))
+		for i, cmd := range msg {
+			mapped[i] = MapCmd(cmd, f)
+		}
+		return mapped
+	case sequenceMsg:
+		mapped := make(sequenceMsg, len(msg))
+		for i, cmd := range msg {
+			mapped[i] = MapCmd(cmd, f)
+		}
+		return mapped
+	case PriorityMsg:
+		return PriorityMsg{Priority: msg.Priority, Cmd: MapCmd(msg.Cmd, f)}
+	case contextCmdMsg:
+		return contextCmdMsg(func(ctx context.Context) Msg {
+			return mapMsg(msg(ctx), f)
+		})
+	case QuitMsg, setWindowTitleMsg, emitJSMsg:
+		return msg
+	}
+	return f(msg)
+}
+
+// MapSend returns a function that wraps messages with f before sending them
+// with send.
+func MapSend(send func(Msg), f func(Msg) Msg) func(Msg) {
+	return func(msg Msg) {
+		send(f(msg))
+	}
+}
+
+// MapSub returns sub with its messages wrapped with f. The key of the
+// subscription is unchanged.
+func MapSub(sub Sub, f func(Msg) Msg) Sub {
+	run := sub.Run
+	sub.Run = func(ctx context.Context, send func(Msg)) {
+		run(ctx, MapSend(send, f))
+	}
+	return sub
+}
+
+// Map returns a component that renders c, wrapping the messages sent by c and
+// the components it renders with f, in the style of Elm's Html.map.
+func Map(c Component, f func(Msg) Msg) Component {
+	return &mapped{Child: c, F: f}
+}
+
+// mapped is the component returned by Map. Its fields are props, so that the
+// instance kept across renders picks up the latest child and function.
+type mapped struct {
+	Core
+	Child Component     `masc:"prop"`
+	F     func(Msg) Msg `masc:"prop"`
+}
+
+func (m *mapped) Render(send func(Msg)) ComponentOrHTML {
+	return m.Child
+}
+
+// mapSend implements sendMapper.
+func (m *mapped) mapSend(send func(Msg)) func(Msg) {
+	return MapSend(send, m.F)
+}
+
+// sendMapper is implemented by components that change the send function
+// passed to themselves and the components they render, see Map.
+type sendMapper interface {
+	mapSend(send func(Msg)) func(Msg)
+}
+
+// TagMsg is the constraint on the message types that tag the messages of a
+// Child: structs with a single field Msg holding the child's message.
+type TagMsg interface {
+	~struct{ Msg Msg }
+}
+
+// Child wraps a child Model within a parent model. The messages of the child,
+// whether returned by its commands, sent by its subscriptions or sent from
+// its view, are tagged with T, so that the parent can route them back to the
+// child with Update.
+//
+// Example:
+//
+//	type counterMsg struct{ Msg masc.Msg }
+//
+//	type parent struct {
+//	    masc.Core
+//	    counter *masc.Child[counterMsg, *counter]
+//	}
+//
+//	func (p *parent) Update(msg masc.Msg) (masc.Model, masc.Cmd) {
+//	    if cmd, ok := p.counter.Update(msg); ok {
+//	        return p, cmd
+//	    }
+//	    ...
+//	}
+//
+//	func (p *parent) Render(send func(masc.Msg)) masc.ComponentOrHTML {
+//	    return elem.Body(p.counter.View())
+//	}
+type Child[T TagMsg, M Model] struct {
+	Model M
+}
+
+// NewChild returns a Child wrapping model, tagging its messages with T.
+func NewChild[T TagMsg, M Model](model M) *Child[T, M] {
+	return &Child[T, M]{Model: model}
+}
+
+// tag wraps msg in T.
+func (c *Child[T, M]) tag(msg Msg) Msg {
+	return T{Msg: msg}
+}
+
+// Init returns the child's initial command, with its messages tagged.
+func (c *Child[T, M]) Init() Cmd {
+	return MapCmd(c.Model.Init(), c.tag)
+}
+
+// Update passes msg to the child's Update if it is tagged with T, and
+// returns the child's command, with its messages tagged, and true. Other
+// messages are left alone and Update returns false.
+func (c *Child[T, M]) Update(msg Msg) (Cmd, bool) {
+	tagged, ok := msg.(T)
+	if !ok {
+		return nil, false
+	}
+	model, cmd := c.Model.Update(struct{ Msg Msg }(tagged).Msg)
+	updated, ok := model.(M)
+	if !ok {
+		panic(fmt.Sprintf("masc: child Update returned %T, expected %T", model, c.Model))
+	}
+	c.Model = updated
+	return MapCmd(cmd, c.tag), true
+}
+
+// Subscriptions returns the child's subscriptions, if it is a Subscriber,
+// with their messages tagged. Their keys are distinct from those of the
+// subscriptions of the parent and of other children.
+func (c *Child[T, M]) Subscriptions() []Sub {
+	s, ok := Model(c.Model).(Subscriber)
+	if !ok {
+		return nil
+	}
+	subs := s.Subscriptions()
+	for i, sub := range subs {
+		sub = MapSub(sub, c.tag)
+		sub.Key = childSubKey[T]{key: sub.Key}
+		subs[i] = sub
+	}
+	return subs
+}
+
+// childSubKey is the key of a subscription of a Child tagging with T.
+type childSubKey[T TagMsg] struct {
+	key interface{}
+}
+
+// View returns the child's view, with the messages it sends tagged.
+func (c *Child[T, M]) View() Component {
+	return Map(c.Model, c.tag)
+}
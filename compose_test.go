@@ -0,0 +1,174 @@
+package masc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type tagA struct{ Msg Msg }
+type tagB struct{ Msg Msg }
+
+func wrapA(msg Msg) Msg { return tagA{Msg: msg} }
+
+func TestMapCmd(t *testing.T) {
+	if MapCmd(nil, wrapA) != nil {
+		t.Fatal("expected a nil command to stay nil")
+	}
+
+	tests := []struct {
+		name string
+		cmd  Cmd
+		want Msg
+	}{
+		{"message", func() Msg { return incrementMsg{} }, tagA{incrementMsg{}}},
+		{"nil", func() Msg { return nil }, nil},
+		{"quit", Quit, QuitMsg{}},
+		{"title", SetWindowTitle("title"), setWindowTitleMsg("title")},
+	}
+	for _, tst := range tests {
+		t.Run(tst.name, func(t *testing.T) {
+			if got := MapCmd(tst.cmd, wrapA)(); got != tst.want {
+				t.Fatalf("expected %v, got %v", tst.want, got)
+			}
+		})
+	}
+
+	inc := func() Msg { return incrementMsg{} }
+	t.Run("batch", func(t *testing.T) {
+		batch := MapCmd(Batch(inc, inc), wrapA)().(BatchMsg)
+		for _, cmd := range batch {
+			if got := cmd(); got != (tagA{incrementMsg{}}) {
+				t.Fatalf("expected the commands of the batch to be mapped, got %v", got)
+			}
+		}
+	})
+	t.Run("sequence", func(t *testing.T) {
+		seq := MapCmd(Sequence(inc, inc), wrapA)().(sequenceMsg)
+		for _, cmd := range seq {
+			if got := cmd(); got != (tagA{incrementMsg{}}) {
+				t.Fatalf("expected the commands of the sequence to be mapped, got %v", got)
+			}
+		}
+	})
+	t.Run("priority", func(t *testing.T) {
+		msg := MapCmd(Prioritize(PriorityHigh, inc), wrapA)().(PriorityMsg)
+		if msg.Priority != PriorityHigh {
+			t.Fatalf("expected the priority to be kept, got %v", msg.Priority)
+		}
+		if got := msg.Cmd(); got != (tagA{incrementMsg{}}) {
+			t.Fatalf("expected the prioritized command to be mapped, got %v", got)
+		}
+	})
+	t.Run("context", func(t *testing.T) {
+		cmd := ContextCmd(func(context.Context) Msg { return incrementMsg{} })
+		msg := MapCmd(cmd, wrapA)().(contextCmdMsg)
+		if got := msg(context.Background()); got != (tagA{incrementMsg{}}) {
+			t.Fatalf("expected the context command to be mapped, got %v", got)
+		}
+	})
+}
+
+type childModel struct {
+	Core
+	n    int
+	subs []Sub
+}
+
+func (m *childModel) Init() Cmd {
+	return func() Msg { return incrementMsg{} }
+}
+
+func (m *childModel) Update(msg Msg) (Model, Cmd) {
+	if _, ok := msg.(incrementMsg); ok {
+		m.n++
+	}
+	return m, func() Msg { return fmt.Sprint(m.n) }
+}
+
+func (m *childModel) Render(send func(Msg)) ComponentOrHTML { return Tag("div") }
+
+func (m *childModel) Subscriptions() []Sub { return m.subs }
+
+func TestChild(t *testing.T) {
+	a := NewChild[tagA](&childModel{
+		subs: []Sub{{Key: "sub", Run: func(ctx context.Context, send func(Msg)) { send(incrementMsg{}) }}},
+	})
+	b := NewChild[tagB](&childModel{
+		subs: []Sub{{Key: "sub"}},
+	})
+
+	if got := a.Init()(); got != (tagA{incrementMsg{}}) {
+		t.Fatalf("expected the initial command to be tagged, got %v", got)
+	}
+
+	if _, ok := a.Update(incrementMsg{}); ok {
+		t.Fatal("expected an untagged message to be left alone")
+	}
+	if _, ok := a.Update(tagB{incrementMsg{}}); ok {
+		t.Fatal("expected a message for another child to be left alone")
+	}
+	cmd, ok := a.Update(tagA{incrementMsg{}})
+	if !ok || a.Model.n != 1 || b.Model.n != 0 {
+		t.Fatalf("expected only the first child to be updated, got %v, %d and %d", ok, a.Model.n, b.Model.n)
+	}
+	if got := cmd(); got != (tagA{"1"}) {
+		t.Fatalf("expected the command to be tagged, got %v", got)
+	}
+
+	subsA, subsB := a.Subscriptions(), b.Subscriptions()
+	if subsA[0].Key == subsB[0].Key {
+		t.Fatal("expected the children's subscription keys to be distinct")
+	}
+	var sent Msg
+	subsA[0].Run(context.Background(), func(msg Msg) { sent = msg })
+	if sent != (tagA{incrementMsg{}}) {
+		t.Fatalf("expected the subscription's messages to be tagged, got %v", sent)
+	}
+}
+
+// sendComponent records the send functions it is rendered with.
+type sendComponent struct {
+	Core
+	sends *[]func(Msg)
+	child ComponentOrHTML
+}
+
+func (c *sendComponent) Render(send func(Msg)) ComponentOrHTML {
+	*c.sends = append(*c.sends, send)
+	return Tag("div", c.child)
+}
+
+func TestMap(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	ts.strings.mock(`global.Get("document").Get("readyState")`, "complete")
+	ts.strings.mock(`global.Get("document").Call("querySelector", "body").Get("nodeName")`, "BODY")
+	ts.truthies.mock(`global.Get("document").Call("querySelector", "body")`, true)
+
+	var sends []func(Msg)
+	inner := &sendComponent{sends: &sends}
+	outer := &sendComponent{
+		sends: &sends,
+		child: Map(inner, func(msg Msg) Msg { return tagB{Msg: msg} }),
+	}
+	var got []Msg
+	RenderBody(&componentFunc{
+		render: func() ComponentOrHTML {
+			return Tag("body", Map(outer, wrapA))
+		},
+	}, func(msg Msg) { got = append(got, msg) })
+
+	if len(sends) != 2 {
+		t.Fatalf("expected both components to render, got %d renders", len(sends))
+	}
+	for _, send := range sends {
+		send(incrementMsg{})
+	}
+	want := []Msg{tagA{incrementMsg{}}, tagA{tagB{incrementMsg{}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,342 @@
+package masc
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DebugEntry is a single step recorded by a Debugger: a message and a snapshot
+// of the model that resulted from handling it. The first entry holds the
+// initial model and a nil Msg.
+type DebugEntry struct {
+	Msg   Msg
+	Model Model
+}
+
+// Debugger records every message handled by a Program together with a
+// snapshot of the resulting model, and can re-render any recorded state
+// through the program's renderer.
+//
+// Snapshots are made the same way components are copied for SkipRender: using
+// the model's Copier implementation if it has one, or a shallow copy
+// otherwise. Models that keep state in slices, maps or pointers that Update
+// mutates in place should implement Copier to produce accurate history.
+//
+// While the debugger shows a historical state the program is paused: messages
+// are still handled and recorded, but the view stays on the selected state
+// until Resume is called or the last entry is selected.
+//
+// Example:
+//
+//	d := masc.NewDebugger(500)
+//	p := masc.NewProgram(model, masc.WithDebugger(d))
+type Debugger struct {
+	mtx     sync.Mutex
+	limit   int
+	entries []DebugEntry
+	// trimmed is set once the limit discarded entries, so that the first
+	// entry is no longer the initial model.
+	trimmed bool
+	// cursor is the index of the entry being shown, or -1 when live.
+	cursor int
+
+	next renderer
+	send func(Msg)
+	// live is the most recent model rendered by the program.
+	live Model
+	// shown is the component currently on screen. It owns the render state
+	// which the next render is reconciled against.
+	shown Component
+}
+
+// NewDebugger returns a Debugger that keeps at most limit entries, discarding
+// the oldest ones first. A limit of zero or less keeps every entry.
+func NewDebugger(limit int) *Debugger {
+	return &Debugger{limit: limit, cursor: -1}
+}
+
+// WithDebugger records the program's messages and models in d.
+func WithDebugger(d *Debugger) ProgramOption {
+	return func(p *Program) {
+		p.debugger = d
+		p.middleware = append(p.middleware, d.middleware)
+	}
+}
+
+// attach records the initial model and wraps the program's renderer so that
+// historical states can be rendered.
+func (d *Debugger) attach(model Model, r renderer) renderer {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.next = r
+	d.entries = nil
+	d.trimmed = false
+	d.cursor = -1
+	d.record(nil, model)
+	return &debugRenderer{d: d}
+}
+
+// middleware records messages and the models resulting from them.
+func (d *Debugger) middleware(next UpdateFunc) UpdateFunc {
+	return func(model Model, msg Msg) (Model, Cmd) {
+		model, cmd := next(model, msg)
+		switch msg.(type) {
+		case QuitMsg, BatchMsg, PriorityMsg, sequenceMsg:
+			// They quit or run commands rather than change the model, and
+			// replaying them would run the commands again.
+		default:
+			d.mtx.Lock()
+			d.record(msg, model)
+			d.mtx.Unlock()
+		}
+		return model, cmd
+	}
+}
+
+// record appends an entry, discarding the oldest entries beyond the limit.
+func (d *Debugger) record(msg Msg, model Model) {
+	d.entries = append(d.entries, DebugEntry{Msg: msg, Model: snapshot(model)})
+	if d.limit > 0 && len(d.entries) > d.limit {
+		drop := len(d.entries) - d.limit
+		copy(d.entries, d.entries[drop:])
+		for i := d.limit; i < len(d.entries); i++ {
+			d.entries[i] = DebugEntry{}
+		}
+		d.entries = d.entries[:d.limit]
+		d.trimmed = true
+		if d.cursor >= 0 {
+			d.cursor -= drop
+			if d.cursor < 0 {
+				d.cursor = 0
+			}
+		}
+	}
+}
+
+// snapshot copies model so later updates do not affect the recorded state.
+func snapshot(model Model) Model {
+	if model == nil {
+		return nil
+	}
+	cpy, ok := copyComponent(model).(Model)
+	if !ok {
+		panic("masc: Copy of a Model must return a Model")
+	}
+	return cpy
+}
+
+// Len returns the number of recorded entries.
+func (d *Debugger) Len() int {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return len(d.entries)
+}
+
+// Entries returns a copy of the recorded entries, oldest first.
+func (d *Debugger) Entries() []DebugEntry {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return append([]DebugEntry(nil), d.entries...)
+}
+
+// Cursor returns the index of the entry being shown. When the debugger is not
+// paused this is the index of the latest entry.
+func (d *Debugger) Cursor() int {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.position()
+}
+
+func (d *Debugger) position() int {
+	if d.cursor < 0 {
+		return len(d.entries) - 1
+	}
+	return d.cursor
+}
+
+// Paused reports whether a historical state is being shown.
+func (d *Debugger) Paused() bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.cursor >= 0
+}
+
+// Back shows the state before the one currently shown.
+func (d *Debugger) Back() {
+	d.mtx.Lock()
+	d.jump(d.position() - 1)
+	d.mtx.Unlock()
+	d.requestRender()
+}
+
+// Forward shows the state after the one currently shown. Moving past the last
+// recorded state resumes the program.
+func (d *Debugger) Forward() {
+	d.mtx.Lock()
+	d.jump(d.position() + 1)
+	d.mtx.Unlock()
+	d.requestRender()
+}
+
+// Jump shows the state recorded at index i. Jumping to the last entry resumes
+// the program.
+func (d *Debugger) Jump(i int) {
+	d.mtx.Lock()
+	d.jump(i)
+	d.mtx.Unlock()
+	d.requestRender()
+}
+
+// Resume returns to the live model and re-renders it.
+func (d *Debugger) Resume() {
+	d.mtx.Lock()
+	d.cursor = -1
+	d.mtx.Unlock()
+	d.requestRender()
+}
+
+// jump selects entry i, or the live model past the last entry.
+func (d *Debugger) jump(i int) {
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(d.entries)-1 {
+		d.cursor = -1
+		return
+	}
+	d.cursor = i
+}
+
+// debugRenderMsg asks the program to render the state selected in its
+// debugger, so that it is rendered by the event loop like any other render of
+// the program rather than racing with them.
+type debugRenderMsg struct{}
+
+// requestRender has the program render the selected state.
+func (d *Debugger) requestRender() {
+	d.mtx.Lock()
+	send := d.send
+	d.mtx.Unlock()
+	if send != nil {
+		// The controls may be used from within the event loop, e.g. in
+		// Update, so don't wait for it. The render shows whatever state is
+		// selected by then.
+		go send(debugRenderMsg{})
+	}
+}
+
+// renderSelected renders the selected state. It is called by the event loop.
+func (d *Debugger) renderSelected() {
+	d.mtx.Lock()
+	c := Component(d.live)
+	if d.cursor >= 0 {
+		// Render a fresh copy so the recorded snapshot is never mutated.
+		c = copyComponent(d.entries[d.cursor].Model)
+	}
+	if c == nil || d.next == nil || d.shown == nil {
+		d.mtx.Unlock()
+		return
+	}
+	if c != d.shown {
+		// Take over the render state of what is on screen, so that the DOM
+		// is reconciled rather than rebuilt.
+		*c.Context() = *d.shown.Context()
+		d.shown = c
+	}
+	next, send := d.next, d.send
+	d.mtx.Unlock()
+	next.render(c, send)
+}
+
+// Replay applies the recorded messages up to entry n to model with Update
+// and returns the result. Commands returned by Update are discarded. Because
+// Update is expected to be deterministic, replaying onto the initial model
+// reproduces the state recorded in entry n.
+//
+// Once the limit has discarded the oldest entries, entry 0 no longer holds
+// the initial model, so the messages are replayed onto a copy of its model
+// instead, and model is ignored.
+func (d *Debugger) Replay(model Model, n int) Model {
+	d.mtx.Lock()
+	entries := append([]DebugEntry(nil), d.entries...)
+	trimmed := d.trimmed
+	d.mtx.Unlock()
+
+	if trimmed && len(entries) > 0 {
+		model = snapshot(entries[0].Model)
+	}
+	for i := 1; i < len(entries) && i <= n; i++ {
+		model, _ = model.Update(entries[i].Msg)
+	}
+	return model
+}
+
+// Overlay returns a component with controls for stepping through the
+// debugger's history. Include it in your model's Render output to use it.
+func (d *Debugger) Overlay() Component {
+	return &debugOverlay{d: d}
+}
+
+// debugRenderer renders the live model, unless the debugger is paused.
+type debugRenderer struct {
+	d *Debugger
+}
+
+func (r *debugRenderer) start() {
+	r.d.mtx.Lock()
+	defer r.d.mtx.Unlock()
+	r.d.next.start()
+}
+
+func (r *debugRenderer) render(c Component, send func(Msg)) {
+	d := r.d
+	d.mtx.Lock()
+	if m, ok := c.(Model); ok {
+		d.live = m
+	}
+	d.send = send
+	if d.cursor >= 0 {
+		d.mtx.Unlock()
+		return
+	}
+	if d.shown != nil && d.shown != c {
+		// Coming back from a historical state, continue from its render.
+		*c.Context() = *d.shown.Context()
+	}
+	d.shown = c
+	next := d.next
+	d.mtx.Unlock()
+	next.render(c, send)
+}
+
+// debugOverlay renders the debugger controls.
+type debugOverlay struct {
+	Core
+	d *Debugger
+}
+
+func (o *debugOverlay) Render(send func(Msg)) ComponentOrHTML {
+	button := func(label string, action func()) *HTML {
+		return Tag("button",
+			Markup(&EventListener{Name: "click", Listener: func(*Event) { action() }}),
+			Text(label),
+		)
+	}
+	return Tag("div",
+		Markup(
+			Class("masc-debugger"),
+			Style("position", "fixed"),
+			Style("bottom", "0"),
+			Style("right", "0"),
+			Style("z-index", "2147483647"),
+			Style("background", "#222"),
+			Style("color", "#eee"),
+			Style("font", "12px monospace"),
+			Style("padding", "4px"),
+		),
+		button("◀", o.d.Back),
+		Text(fmt.Sprintf(" %d / %d ", o.d.Cursor(), o.d.Len()-1)),
+		button("▶", o.d.Forward),
+		If(o.d.Paused(), button("Resume", o.d.Resume)),
+	)
+}
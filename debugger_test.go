@@ -0,0 +1,132 @@
+package masc
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingRenderer records the components it is asked to render.
+type recordingRenderer struct {
+	mtx      sync.Mutex
+	rendered []Component
+}
+
+func (r *recordingRenderer) start() {}
+
+func (r *recordingRenderer) render(c Component, _ func(Msg)) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.rendered = append(r.rendered, c)
+}
+
+func (r *recordingRenderer) count() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.rendered)
+}
+
+func (r *recordingRenderer) last() Component {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.rendered[len(r.rendered)-1]
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDebugger(t *testing.T) {
+	rec := &recordingRenderer{}
+	d := NewDebugger(0)
+	p := NewProgram(&counterModel{}, WithDebugger(d), WithoutFrameCoalescing(), func(p *Program) { p.renderer = rec })
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := p.Run()
+		errs <- err
+	}()
+
+	// Only the messages of the commands of a Batch or Sequence are recorded,
+	// so that replaying does not run the commands again.
+	inc := func() Msg { return incrementMsg{} }
+	p.Send(incrementMsg{})
+	p.Send(BatchMsg{inc})
+	p.Send(sequenceMsg{inc})
+	waitFor(t, func() bool { return d.Len() == 4 })
+	for i, e := range d.Entries()[1:] {
+		if _, ok := e.Msg.(incrementMsg); !ok {
+			t.Fatalf("expected entry %d to record an incrementMsg, got %T", i+1, e.Msg)
+		}
+	}
+
+	shown := func() int {
+		return rec.last().(*counterModel).n
+	}
+
+	d.Back()
+	waitFor(t, func() bool { return shown() == 2 })
+	if !d.Paused() || d.Cursor() != 2 {
+		t.Fatalf("after Back: paused=%v cursor=%d shown=%d", d.Paused(), d.Cursor(), shown())
+	}
+
+	// Messages are still recorded while paused, but not rendered.
+	renders := rec.count()
+	p.Send(incrementMsg{})
+	waitFor(t, func() bool { return d.Len() == 5 })
+	if rec.count() != renders {
+		t.Fatal("expected no live renders while paused")
+	}
+
+	d.Jump(0)
+	waitFor(t, func() bool { return shown() == 0 })
+	d.Forward()
+	waitFor(t, func() bool { return shown() == 1 })
+	if n := d.Entries()[1].Model.(*counterModel).n; n != 1 {
+		t.Fatalf("expected recorded snapshot to be unchanged, got %d", n)
+	}
+
+	d.Resume()
+	waitFor(t, func() bool { return shown() == 4 })
+	if d.Paused() {
+		t.Fatalf("after Resume: paused=%v shown=%d", d.Paused(), shown())
+	}
+
+	if n := d.Replay(&counterModel{}, 3).(*counterModel).n; n != 3 {
+		t.Fatalf("expected replay to reach 3, got %d", n)
+	}
+
+	p.Quit()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDebuggerLimit(t *testing.T) {
+	d := NewDebugger(2)
+	d.attach(&counterModel{}, &recordingRenderer{})
+	update := d.middleware(func(m Model, msg Msg) (Model, Cmd) { return m.Update(msg) })
+
+	var m Model = &counterModel{}
+	for i := 0; i < 3; i++ {
+		m, _ = update(m, incrementMsg{})
+	}
+	entries := d.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if n := entries[0].Model.(*counterModel).n; n != 2 {
+		t.Fatalf("expected oldest entry to be 2, got %d", n)
+	}
+	// The initial model is gone, so replay starts from the oldest entry.
+	if n := d.Replay(&counterModel{}, 1).(*counterModel).n; n != 3 {
+		t.Fatalf("expected replay to reach 3, got %d", n)
+	}
+}
@@ -8,9 +8,6 @@ import (
 // defaultFrameBudget is the target frame budget in milliseconds (1000ms / 60fps).
 const defaultFrameBudget = 1000.0 / 60.0
 
-// batch renderer singleton.
-var batch = &batchRenderer{idx: make(map[Component]int)}
-
 // HTMLStats tracks HTML element creation for debugging memory leaks
 var (
 	HTMLCreated    int64 // Number of HTML elements created via Tag/Text
@@ -53,6 +50,11 @@ type Component interface {
 	// If Render returns nil, the component will render as nothing (in reality,
 	// a noscript tag, which has no display or action, and is compatible with
 	// Vecty's diffing algorithm).
+	//
+	// If Render returns a List or KeyedList, the component renders as a
+	// fragment: the elements of the list become children of the element the
+	// component is a child of, with no wrapper element. The body component, and
+	// components rerendered on their own, must not render fragments.
 	Render(send func(Msg)) ComponentOrHTML
 
 	// Context returns the components context, which is used internally by
@@ -167,6 +169,11 @@ type HTML struct {
 	// lastRendered child tracks the last child that was rendered, across List
 	// boundaries.
 	lastRenderedChild *HTML
+	// portal holds the children rendered elsewhere by a Portal.
+	portal *portal
+	// hydrated is set if node is a server-rendered node adopted by Hydrate,
+	// which is already in place and must not be inserted by the parent.
+	hydrated bool
 }
 
 // TagName returns the HTML tag for element nodes, or empty for text nodes.
@@ -244,7 +251,7 @@ func (h *HTML) reconcileText(prev *HTML) {
 	}
 }
 
-func (h *HTML) reconcile(prev *HTML, send func(Msg)) []Mounter {
+func (h *HTML) reconcile(prev *HTML, send func(Msg), batch *batchRenderer) []Mounter {
 	// Check for compatible tag and mutate previous instance on match, otherwise start fresh
 	switch {
 	case prev != nil && h.tag == "" && prev.tag == "":
@@ -264,7 +271,11 @@ func (h *HTML) reconcile(prev *HTML, send func(Msg)) []Mounter {
 		}
 		// Release event listeners from the old node being replaced
 		prev.releaseEventListeners()
-		h.createNode()
+		if batch.hydrating() {
+			defer batch.hydration.adopt(h)()
+		} else {
+			h.createNode()
+		}
 	}
 
 	if !h.node.Equal(prev.node) {
@@ -274,7 +285,8 @@ func (h *HTML) reconcile(prev *HTML, send func(Msg)) []Mounter {
 		h.reconcileProperties(prev)
 	}
 
-	return h.reconcileChildren(prev, send)
+	pendingMounts := h.reconcilePortal(prev, send, batch)
+	return append(pendingMounts, h.reconcileChildren(prev, send, batch)...)
 }
 
 // releaseEventListeners releases all js.Func wrappers for event listeners.
@@ -340,9 +352,11 @@ func (h *HTML) removeProperties(prev *HTML) {
 
 // reconcileChildren reconciles children of the current HTML against a previous
 // render's DOM nodes.
-func (h *HTML) reconcileChildren(prev *HTML, send func(Msg)) (pendingMounts []Mounter) {
+func (h *HTML) reconcileChildren(prev *HTML, send func(Msg), batch *batchRenderer) (pendingMounts []Mounter) {
 	hasKeyedChildren := len(h.keyedChildren) > 0
 	prevHadKeyedChildren := len(prev.keyedChildren) > 0
+	// Keyed children are moved into place once all of them are reconciled.
+	var moves *keyedMoves
 	for i, nextChild := range h.children {
 		// Determine concrete type if necessary.
 		switch v := nextChild.(type) {
@@ -398,11 +412,23 @@ func (h *HTML) reconcileChildren(prev *HTML, send func(Msg)) (pendingMounts []Mo
 		// can not be determined by children index, so skip if keyed.
 		if (i >= len(prev.children) && !hasKeyedChildren) || isNew {
 			if nextChildList, ok := nextChild.(KeyedList); ok {
-				pendingMounts = append(pendingMounts, nextChildList.reconcile(h, nil, send)...)
+				pendingMounts = append(pendingMounts, nextChildList.reconcile(h, nil, send, batch)...)
+				continue
+			}
+			nextChildRender, skip, mounters := render(nextChild, nil, send, batch)
+			if skip {
 				continue
 			}
-			nextChildRender, skip, mounters := render(nextChild, nil, send)
-			if skip || nextChildRender == nil {
+			// Fragments insert their nodes by reconciling their list.
+			if l, ok := fragment(nextChild); ok {
+				pendingMounts = append(pendingMounts, l.reconcile(h, nil, send, batch)...)
+				pendingMounts = append(pendingMounts, mounters...)
+				if m, ok := nextChild.(Mounter); ok {
+					pendingMounts = append(pendingMounts, m)
+				}
+				continue
+			}
+			if nextChildRender == nil {
 				continue
 			}
 			pendingMounts = append(pendingMounts, mounters...)
@@ -424,6 +450,9 @@ func (h *HTML) reconcileChildren(prev *HTML, send func(Msg)) (pendingMounts []Mo
 		}
 		// Find previous keyed sibling if exists, and mutate from there.
 		if hasKeyedChildren {
+			if moves == nil {
+				moves = newKeyedMoves(h, prev)
+			}
 			if prevKeyedChild, ok := prev.keyedChildren[nextKey]; ok {
 				prevChild = prevKeyedChild
 			} else {
@@ -457,10 +486,36 @@ func (h *HTML) reconcileChildren(prev *HTML, send func(Msg)) (pendingMounts []Mo
 			h.insertBeforeNode = h.insertBeforeNode.Get("nextSibling")
 		}
 
+		// Keyed lists and fragments whose DOM nodes are all reused need not
+		// move, unless their order changed.
+		var prevNodes []*HTML
+		if hasKeyedChildren {
+			prevNodes = childNodes(prevChild, nil)
+		}
+
+		// A previous fragment is reconciled against the next render of the
+		// same component. Otherwise, its nodes are removed here, as those of a
+		// previous list are below.
+		prevFragment, prevIsFragment := fragment(prevChild)
+		if prevIsFragment && !sameType(nextChild, prevChild) {
+			prevFragment.remove(h)
+			unmount(prevChild)
+			if hasKeyedChildren {
+				delete(prev.keyedChildren, nextKey)
+			}
+			prevChild = nil
+			prevIsFragment = false
+		}
+
 		// If the next child is a list, reconcile its elements in-place, and
 		// we're done.
 		if nextChildList, ok := nextChild.(KeyedList); ok {
-			pendingMounts = append(pendingMounts, nextChildList.reconcile(h, prevChild, send)...)
+			pendingMounts = append(pendingMounts, nextChildList.reconcile(h, prevChild, send, batch)...)
+			if hasKeyedChildren {
+				delete(prev.keyedChildren, nextKey)
+				nodes := nextChildList.nodes(nil)
+				moves.add(nextKey, nodes, sameNodes(prevNodes, nodes))
+			}
 			continue
 		}
 
@@ -471,28 +526,8 @@ func (h *HTML) reconcileChildren(prev *HTML, send func(Msg)) (pendingMounts []Mo
 			prevChild = nil
 		}
 
-		// If we're keyed, find the next DOM node from the previous render to
-		// insert before, for reordering.
-		var (
-			insertBeforeKeyedNode jsObject
-			stableKey             bool
-		)
-		if hasKeyedChildren {
-			insertBeforeKeyedNode = h.lastRenderedChild.nextSibling()
-			// If the next node is our old node, mark key as stable, to avoid
-			// unnecessary insertion.
-			if prevChildRender != nil {
-				if insertBeforeKeyedNode == nil {
-					stableKey = true
-				} else if prevChildRender.node.Equal(insertBeforeKeyedNode) {
-					stableKey = true
-					insertBeforeKeyedNode = nil
-				}
-			}
-		}
-
 		// Determine the next child render.
-		nextChildRender, skip, mounters := render(nextChild, prevChild, send)
+		nextChildRender, skip, mounters := render(nextChild, prevChild, send, batch)
 		if nextChildRender != nil && prevChildRender != nil && nextChildRender == prevChildRender {
 			panic("masc: next child render must not equal previous child render (did the child Render illegally return a stored render variable?)")
 		}
@@ -516,11 +551,53 @@ func (h *HTML) reconcileChildren(prev *HTML, send func(Msg)) (pendingMounts []Mo
 				}
 			}
 		}
+
+		// If the next child is a fragment, reconcile its list against the
+		// previous render, and advance past its nodes.
+		if l, ok := fragment(nextChild); ok {
+			if !skip {
+				switch {
+				case prevIsFragment:
+					pendingMounts = append(pendingMounts, l.reconcile(h, prevFragment, send, batch)...)
+				case prevChildRender != nil:
+					// Insert the list where the previous node was.
+					if h.insertBeforeNode == nil {
+						h.insertBeforeNode = prevChildRender.nextSibling()
+					}
+					h.removeChild(prevChildRender)
+					fallthrough
+				default:
+					pendingMounts = append(pendingMounts, l.reconcile(h, nil, send, batch)...)
+				}
+				pendingMounts = append(pendingMounts, mounters...)
+				if m := mountUnmount(nextChild, prevChild); m != nil {
+					pendingMounts = append(pendingMounts, m)
+				}
+			}
+			if hasKeyedChildren {
+				delete(prev.keyedChildren, nextKey)
+				nodes := l.nodes(nil)
+				moves.add(nextKey, nodes, sameNodes(prevNodes, nodes))
+				continue
+			}
+			l.advance(h)
+			continue
+		}
 		if skip {
+			if hasKeyedChildren {
+				delete(prev.keyedChildren, nextKey)
+				moves.add(nextKey, childNodes(nextChild, nil), true)
+			}
 			continue
 		}
 		pendingMounts = append(pendingMounts, mounters...)
 
+		// If the previous child was a fragment, remove its nodes, so that the
+		// next child render is inserted in their place.
+		if prevIsFragment {
+			prevFragment.remove(h)
+		}
+
 		// Perform the final reconciliation action for nextChildRender and
 		// prevChildRender. Replace, remove, insert or append the DOM nodes.
 		switch {
@@ -531,37 +608,24 @@ func (h *HTML) reconcileChildren(prev *HTML, send func(Msg)) (pendingMounts []Mo
 				pendingMounts = append(pendingMounts, m)
 			}
 
-			if hasKeyedChildren && (prevChildRender != nil && prevChildRender.node.Equal(nextChildRender.node)) {
-				// We are re-using the name node. Remove the children from
-				// keyedChildren so that we don't remove it when we remove dangling
-				// children below.
+			// Replace the previous node (may be NOOP for equivalent nodes).
+			replaceNode(nextChildRender.node, prevChildRender.node)
+			if hasKeyedChildren {
+				// The next node took the place of the previous one. Remove
+				// the child from keyedChildren so that we don't remove it when
+				// we remove dangling children below, and move it into place
+				// below.
 				delete(prev.keyedChildren, nextKey)
+				moves.add(nextKey, []*HTML{nextChildRender}, true)
 			}
-
-			// If we do not have keyed siblings, or the key is stable, replace
-			// the previous node (may be NOOP for equivalent nodes).
-			if !hasKeyedChildren || stableKey {
-				replaceNode(nextChildRender.node, prevChildRender.node)
-				continue
-			}
-			// Moving keyed children need to be inserted (which moves existing
-			// nodes), rather than replacing the previous child at this
-			// position.
-			if insertBeforeKeyedNode != nil {
-				// Insert before the next sibling, if we have one.
-				h.insertBefore(insertBeforeKeyedNode, nextChildRender)
-				continue
-			}
-			h.insertBefore(h.insertBeforeNode, nextChildRender)
 		case nextChildRender == nil && prevChildRender != nil:
 			h.removeChild(prevChildRender)
 		case nextChildRender != nil && prevChildRender == nil:
 			if m, ok := nextChild.(Mounter); ok {
 				pendingMounts = append(pendingMounts, m)
 			}
-			if insertBeforeKeyedNode != nil {
-				// Insert before the next keyed sibling, if we have one.
-				h.insertBefore(insertBeforeKeyedNode, nextChildRender)
+			if hasKeyedChildren {
+				moves.add(nextKey, []*HTML{nextChildRender}, false)
 				continue
 			}
 			h.insertBefore(h.insertBeforeNode, nextChildRender)
@@ -570,6 +634,10 @@ func (h *HTML) reconcileChildren(prev *HTML, send func(Msg)) (pendingMounts []Mo
 		}
 	}
 
+	if moves != nil {
+		moves.apply(h)
+	}
+
 	// If dealing with keyed siblings, remove all prev.keyedChildren which are
 	// leftovers / ones we did not find a match for above.
 	if prevHadKeyedChildren && hasKeyedChildren {
@@ -600,6 +668,12 @@ func (h *HTML) removeChildren(prevChildren []ComponentOrHTML) {
 			prevChildList.remove(h)
 			continue
 		}
+		if prevFragment, ok := fragment(prevChild); ok {
+			// Previous child was a fragment, so remove the nodes of its list.
+			prevFragment.remove(h)
+			unmount(prevChild)
+			continue
+		}
 		prevChildRender := extractHTML(prevChild)
 		if prevChildRender == nil {
 			continue
@@ -647,8 +721,13 @@ func (h *HTML) appendChild(child *HTML) {
 }
 
 // insertBefore inserts the provided child before the provided DOM node. If the
-// DOM node is nil, the child will be appended instead.
+// DOM node is nil, the child will be appended instead. Hydrated children are
+// already in place, and are only inserted when they move later on.
 func (h *HTML) insertBefore(node jsObject, child *HTML) {
+	if child.hydrated {
+		child.hydrated = false
+		return
+	}
 	if node == nil {
 		h.appendChild(child)
 		return
@@ -696,7 +775,7 @@ func (l KeyedList) Key() interface{} {
 // reconcile reconciles the keyedList against the DOM node in a separate
 // context, unless keyed. Uses the currently known insertion point from the
 // parent to insert children at the correct position.
-func (l KeyedList) reconcile(parent *HTML, prevChild ComponentOrHTML, send func(Msg)) (pendingMounts []Mounter) {
+func (l KeyedList) reconcile(parent *HTML, prevChild ComponentOrHTML, send func(Msg), batch *batchRenderer) (pendingMounts []Mounter) {
 	// Effectively become the parent (copy its scope) so that we can reconcile
 	// our children against the prev child.
 	l.html.node = parent.node
@@ -705,12 +784,12 @@ func (l KeyedList) reconcile(parent *HTML, prevChild ComponentOrHTML, send func(
 
 	switch v := prevChild.(type) {
 	case KeyedList:
-		pendingMounts = l.html.reconcileChildren(v.html, send)
+		pendingMounts = l.html.reconcileChildren(v.html, send, batch)
 	case *HTML, Component, nil:
 		if v == nil {
 			// No previous element, so reconcile against a parent with no
 			// children so all of our elements are added.
-			pendingMounts = l.html.reconcileChildren(&HTML{node: parent.node}, send)
+			pendingMounts = l.html.reconcileChildren(&HTML{node: parent.node}, send, batch)
 		} else {
 			// Build a previous render containing just the prevChild to be
 			// replaced by this list
@@ -718,7 +797,7 @@ func (l KeyedList) reconcile(parent *HTML, prevChild ComponentOrHTML, send func(
 			if keyer, ok := prevChild.(Keyer); ok && keyer.Key() != nil {
 				prev.keyedChildren = map[interface{}]ComponentOrHTML{keyer.Key(): prevChild}
 			}
-			pendingMounts = l.html.reconcileChildren(prev, send)
+			pendingMounts = l.html.reconcileChildren(prev, send, batch)
 		}
 	default:
 		panic("masc: internal error (unexpected ComponentOrHTML type " + reflect.TypeOf(v).String() + ")")
@@ -736,6 +815,60 @@ func (l KeyedList) reconcile(parent *HTML, prevChild ComponentOrHTML, send func(
 	return pendingMounts
 }
 
+// advance moves the insertion point of the parent past the DOM nodes of the
+// list, as if the list had been reconciled by it.
+func (l KeyedList) advance(parent *HTML) {
+	for _, child := range l.nodes(nil) {
+		if parent.insertBeforeNode != nil && parent.insertBeforeNode.Equal(child.node) {
+			parent.insertBeforeNode = parent.insertBeforeNode.Get("nextSibling")
+		}
+		parent.lastRenderedChild = child
+	}
+}
+
+// nodes appends the rendered HTML of the list's children to out, in DOM
+// order, descending into nested lists and fragments.
+func (l KeyedList) nodes(out []*HTML) []*HTML {
+	for _, child := range l.html.children {
+		out = childNodes(child, out)
+	}
+	return out
+}
+
+// childNodes appends the rendered HTML of child to out, in DOM order. Lists
+// and fragments render any number of nodes, other children at most one.
+func childNodes(child ComponentOrHTML, out []*HTML) []*HTML {
+	if list, ok := child.(KeyedList); ok {
+		return list.nodes(out)
+	}
+	if list, ok := fragment(child); ok {
+		return list.nodes(out)
+	}
+	if h := extractHTML(child); h != nil && h.node != nil {
+		out = append(out, h)
+	}
+	return out
+}
+
+// fragment returns the list rendered by e, if e is a Component whose Render
+// method, or that of the Component it rendered, returned a List or KeyedList.
+// Fragments have no node of their own: the nodes of their list are children
+// of the element the fragment is a child of.
+func fragment(e ComponentOrHTML) (KeyedList, bool) {
+	c, ok := e.(Component)
+	for ok {
+		switch v := c.Context().prevRender.(type) {
+		case KeyedList:
+			return v, true
+		case Component:
+			c = v
+		default:
+			ok = false
+		}
+	}
+	return KeyedList{}, false
+}
+
 // remove keyedList elements from the parent.
 func (l KeyedList) remove(parent *HTML) {
 	// Become the parent so that we can remove all of our children and get an
@@ -781,7 +914,7 @@ func Text(text string, m ...MarkupOrChild) *HTML {
 }
 
 // Rerender causes the body of the given Component (i.e. the HTML returned by
-// the Component's Render method) to be re-rendered.
+// the Component's Render method) to be re-rendered, as part of batch b.
 //
 // If the Component has not been rendered before, Rerender panics. If the
 // Component was previously unmounted, Rerender is no-op.
@@ -790,7 +923,7 @@ func Text(text string, m ...MarkupOrChild) *HTML {
 // there is no guarantee that a calls to Rerender will map 1:1 with calls to
 // the Component's Render method. For example, two calls to Rerender may
 // result in only one call to the Component's Render method.
-func rerender(c Component, send func(Msg)) {
+func rerender(b *batchRenderer, c Component, send func(Msg)) {
 	if c == nil {
 		panic("masc: Rerender illegally called with a nil Component argument")
 	}
@@ -800,18 +933,32 @@ func rerender(c Component, send func(Msg)) {
 	if c.Context().unmounted {
 		return
 	}
-	batch.add(c, send)
+	b.add(c, send)
 }
 
 // batchRenderer handles component re-renders by queueing and deduplicating
 // them, to be rendered on the next animation frame (via requestAnimationFrame).
+// Each renderer has its own batch, so that programs rendering into different
+// nodes of a page do not share their renders. The batch is passed down through
+// every render, which reports to the tracer of its program.
 type batchRenderer struct {
+	// program owns the batch, or is nil for the batch of a render that is not
+	// driven by a Program. Its panic handler and clock are used for the
+	// animation frames.
+	program *Program
 	// batch contains the list of pending components to render.
 	batch []Component
 	// idx maps components to batch indexes to allow dedup, retaining order.
 	idx map[Component]int
 	// scheduled tracks whether a batch has been scheduled for processing.
 	scheduled bool
+	// hydration is set while the first render adopts server-rendered nodes.
+	hydration *hydration
+}
+
+// newBatchRenderer returns an empty batch owned by p, which may be nil.
+func newBatchRenderer(p *Program) *batchRenderer {
+	return &batchRenderer{program: p, idx: make(map[Component]int)}
 }
 
 // add a Component to the pending batch.
@@ -833,7 +980,7 @@ func (b *batchRenderer) add(c Component, send func(Msg)) {
 	// the next frame.
 	if !b.scheduled {
 		b.scheduled = true
-		requestAnimationFrame(b.render, send)
+		requestAnimationFrame(b.program, b.render, send)
 	}
 }
 
@@ -862,13 +1009,14 @@ func (b *batchRenderer) render(startTime float64, send func(Msg)) {
 
 		// Check for remaining time budget, targeting 60fps (~16ms per frame).
 		if i > 0 {
-			elapsed := global().Get("performance").Call("now").Float() - startTime
+			elapsed := frameTime(b.program) - startTime
 			budgetRemaining := defaultFrameBudget - elapsed
 			avgRenderTime := elapsed / float64(i)
 			// If the budget remaining is less than 2 times the average
 			// Component render time, push the remainder of the batch to the
 			// next frame.
 			if budgetRemaining < avgRenderTime*2 {
+				b.traceOverrun(elapsed, len(pending)-i)
 				b.batch = pending[i:]
 				for i, c := range b.batch {
 					b.idx[c] = i
@@ -879,16 +1027,19 @@ func (b *batchRenderer) render(startTime float64, send func(Msg)) {
 
 		// Perform render.
 		prevHTML := extractHTML(c.Context().prevRender)
-		nextHTML, skip, pendingMounts := renderComponent(c, c, send)
+		nextHTML, skip, pendingMounts := renderComponent(c, c, send, b)
 		if skip {
 			continue
 		}
+		if nextHTML == nil {
+			panic("masc: a rerendered Component must not render a fragment")
+		}
 		replaceNode(nextHTML.node, prevHTML.node)
 		mount(pendingMounts...)
 	}
 
 	// Schedule next frame.
-	requestAnimationFrame(b.render, send)
+	requestAnimationFrame(b.program, b.render, send)
 }
 
 // extractHTML returns the *HTML from a ComponentOrHTML.
@@ -900,6 +1051,9 @@ func extractHTML(e ComponentOrHTML) *HTML {
 		return v
 	case Component:
 		return extractHTML(v.Context().prevRender)
+	case KeyedList:
+		// Fragments render no single element.
+		return nil
 	}
 	// fallback for unsupported types; will panic
 	panic("masc: internal error (unexpected ComponentOrHTML type " + reflect.TypeOf(e).String() + ")")
@@ -988,15 +1142,15 @@ func copyProps(src, dst Component) {
 // 4. nextChild == Component && prevChild == Component
 // 5. nextChild == Component && prevChild == *HTML
 // 6. nextChild == Component && prevChild == nil.
-func render(next, prev ComponentOrHTML, send func(Msg)) (nextHTML *HTML, skip bool, pendingMounts []Mounter) {
+func render(next, prev ComponentOrHTML, send func(Msg), batch *batchRenderer) (nextHTML *HTML, skip bool, pendingMounts []Mounter) {
 	switch v := next.(type) {
 	case *HTML:
 		// Cases 1, 2 and 3 above. Reconcile against the prevRender.
-		pendingMounts = v.reconcile(extractHTML(prev), send)
+		pendingMounts = v.reconcile(extractHTML(prev), send, batch)
 		return v, false, pendingMounts
 	case Component:
 		// Cases 4, 5, and 6 above.
-		return renderComponent(v, prev, send)
+		return renderComponent(v, prev, send, batch)
 	case nil:
 		return nil, false, nil
 	}
@@ -1009,7 +1163,7 @@ func render(next, prev ComponentOrHTML, send func(Msg)) (nextHTML *HTML, skip bo
 // renderComponent handles rendering the given Component into *HTML. If skip ==
 // true is returned, the Component's SkipRender method has signaled the
 // component does not need to be rendered and h == nil is returned.
-func renderComponent(next Component, prev ComponentOrHTML, send func(Msg)) (nextHTML *HTML, skip bool, pendingMounts []Mounter) {
+func renderComponent(next Component, prev ComponentOrHTML, send func(Msg), batch *batchRenderer) (nextHTML *HTML, skip bool, pendingMounts []Mounter) {
 	// If we had a component last render, and it's of compatible type, operate
 	// on the previous instance.
 	if prevComponent, ok := prev.(Component); ok && sameType(next, prevComponent) {
@@ -1022,6 +1176,16 @@ func renderComponent(next Component, prev ComponentOrHTML, send func(Msg)) (next
 		next = prevComponent
 	}
 
+	// Components returned by Map change the send function of their subtree.
+	if m, ok := next.(sendMapper); ok {
+		send = m.mapSend(send)
+	}
+
+	// Error boundaries contain the panics of their Child's render.
+	if b, ok := next.(*ErrorBoundary); ok && b.catches() {
+		defer b.recover(send, batch, &nextHTML, &skip, &pendingMounts)
+	}
+
 	// Before rendering, consult the Component's SkipRender method to see if we
 	// should skip rendering or not.
 	//nolint:nestif
@@ -1038,16 +1202,24 @@ func renderComponent(next Component, prev ComponentOrHTML, send func(Msg)) (next
 	}
 
 	// Render the component into HTML, handling nil renders.
+	start := batch.now()
 	nextRender := next.Render(send)
+	rendered := batch.now()
 	prevRender := next.Context().prevRender
-	if nextRender == nil {
+	switch v := nextRender.(type) {
+	case nil:
 		// nil renders are translated into noscript tags.
 		nextRender = Tag("noscript")
+	case List:
+		nextRender = KeyedList{html: &HTML{children: v}}
 	}
 
 	switch v := nextRender.(type) {
+	case KeyedList:
+		// The component is a fragment, whose list is reconciled by the parent
+		// element, see fragment.
 	case Component:
-		nextHTML, skip, pendingMounts = renderComponent(v, prevRender, send)
+		nextHTML, skip, pendingMounts = renderComponent(v, prevRender, send, batch)
 		if skip {
 			return nextHTML, skip, pendingMounts
 		}
@@ -1061,7 +1233,7 @@ func renderComponent(next Component, prev ComponentOrHTML, send func(Msg)) (next
 		}
 		nextHTML = v
 		// Reconcile the actual rendered HTML.
-		pendingMounts = nextHTML.reconcile(extractHTML(prev), send)
+		pendingMounts = nextHTML.reconcile(extractHTML(prev), send, batch)
 	default:
 		panic("masc: internal error (unexpected ComponentOrHTML type " + reflect.TypeOf(v).String() + ")")
 	}
@@ -1075,6 +1247,7 @@ func renderComponent(next Component, prev ComponentOrHTML, send func(Msg)) (next
 	next.Context().prevRender = nextRender
 	next.Context().prevRenderComponent = copyComponent(next)
 	next.Context().unmounted = false
+	batch.traceRender(next, start, rendered)
 	return nextHTML, false, pendingMounts
 }
 
@@ -1136,8 +1309,9 @@ func unmount(e ComponentOrHTML) {
 		}
 		c.Context().unmounted = true
 		c.Context().mounted = false
-		if prevRenderComponent, ok := c.Context().prevRender.(Component); ok {
-			unmount(prevRenderComponent)
+		switch prevRender := c.Context().prevRender.(type) {
+		case Component, KeyedList:
+			unmount(prevRender)
 		}
 	}
 
@@ -1152,6 +1326,9 @@ func unmount(e ComponentOrHTML) {
 		for _, child := range h.children {
 			unmount(child)
 		}
+		if h.portal != nil {
+			h.portal.close()
+		}
 		// Release event listener wrappers to prevent memory leaks
 		h.releaseEventListeners()
 	}
@@ -1176,8 +1353,14 @@ func unmount(e ComponentOrHTML) {
 //	}
 //	select{} // run Go forever
 func RenderBody(body Component, send func(Msg)) {
+	renderBody(newBatchRenderer(nil), body, send)
+}
+
+// renderBody renders body as the document body, queueing its rerenders in
+// batch.
+func renderBody(batch *batchRenderer, body Component, send func(Msg)) {
 	target := global().Get("document").Call("querySelector", "body")
-	err := renderIntoNode("RenderBody", target, body, send)
+	err := renderIntoNode("RenderBody", target, body, send, batch)
 	if err != nil {
 		panic(err)
 	}
@@ -1213,20 +1396,23 @@ func (e InvalidTargetError) Error() string {
 // an error of type ElementMismatchError is returned.
 func RenderInto(selector string, c Component, send func(Msg)) error {
 	target := global().Get("document").Call("querySelector", selector)
-	return renderIntoNode("RenderInto", target, c, send)
+	return renderIntoNode("RenderInto", target, c, send, newBatchRenderer(nil))
 }
 
-func renderIntoNode(methodName string, node jsObject, c Component, send func(Msg)) error {
+func renderIntoNode(methodName string, node jsObject, c Component, send func(Msg), batch *batchRenderer) error {
 	if !node.Truthy() {
 		return InvalidTargetError{method: methodName}
 	}
 	// block batch until we're done
 	batch.scheduled = true
-	nextRender, skip, pendingMounts := renderComponent(c, nil, send)
+	nextRender, skip, pendingMounts := renderComponent(c, nil, send, batch)
 	if skip {
 		panic("masc: " + methodName + ": Component.SkipRender illegally returned true")
 	}
 	expectTag := toLower(node.Get("nodeName").String())
+	if nextRender == nil {
+		return ElementMismatchError{method: methodName, got: "fragment", want: expectTag}
+	}
 	if nextRender.tag != expectTag {
 		return ElementMismatchError{method: methodName, got: nextRender.tag, want: expectTag}
 	}
@@ -1241,7 +1427,7 @@ func renderIntoNode(methodName string, node jsObject, c Component, send func(Msg
 			if m, ok := c.(Mounter); ok {
 				mount(m)
 			}
-			requestAnimationFrame(batch.render, send)
+			requestAnimationFrame(batch.program, batch.render, send)
 			return undefined()
 		})
 		doc.Call("addEventListener", "DOMContentLoaded", cb)
@@ -1252,7 +1438,7 @@ func renderIntoNode(methodName string, node jsObject, c Component, send func(Msg
 	if m, ok := c.(Mounter); ok {
 		mount(m)
 	}
-	requestAnimationFrame(batch.render, send)
+	requestAnimationFrame(batch.program, batch.render, send)
 	return nil
 }
 
@@ -1286,5 +1472,3 @@ type jsObject interface {
 	Int() int
 	Float() float64
 }
-
-var isTest bool
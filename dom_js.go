@@ -41,13 +41,13 @@ func (h *HTML) Node() js.Value {
 // If the Component's Render method does not return an element of the same type,
 // an error of type ElementMismatchError is returned.
 func RenderIntoNode(node js.Value, c Component, send func(Msg)) error {
-	return renderIntoNode("RenderIntoNode", wrapObject(node), c, send)
+	return renderIntoNode("RenderIntoNode", wrapObject(node), c, send, newBatchRenderer(nil))
 }
 
 // RenderTo configures the renderer to render the model to the passed DOM node.
 func RenderTo(rootNode js.Value) ProgramOption {
 	return func(p *Program) {
-		p.renderer = newNodeRenderer(wrapObject(rootNode))
+		p.renderer = newNodeRenderer(p, wrapObject(rootNode))
 	}
 }
 
@@ -58,6 +58,8 @@ func toLower(s string) string {
 	return js.Global().Get("String").Get("prototype").Get("toLowerCase").Call("call", js.ValueOf(s)).String()
 }
 
+// globalValue is the window. Unlike the rendering state, it is shared by all
+// programs on the page. Tests replace it with a mock.
 var globalValue jsObject
 
 func global() jsObject {
@@ -178,8 +180,9 @@ func (w wrappedObject) Float() float64 {
 	return w.j.Float()
 }
 
-// requestAnimationFrame calls the native JS function of the same name.
-func requestAnimationFrame(callback func(float64, func(Msg)), send func(Msg)) {
+// requestAnimationFrame calls the native JS function of the same name. Panics
+// in callback are handled by p's panic handler, if p is not nil.
+func requestAnimationFrame(p *Program, callback func(float64, func(Msg)), send func(Msg)) {
 	var cb jsFunc
 	atomic.AddInt64(&JsFuncRAF, 1)
 	cb = funcOf(func(_ jsObject, args []jsObject) interface{} {
@@ -190,12 +193,12 @@ func requestAnimationFrame(callback func(float64, func(Msg)), send func(Msg)) {
 			if r := recover(); r != nil {
 				js.Global().Get("console").Call("log", "MASC caught panic in render callback:", r)
 
-				if currentProgram != nil {
+				if p != nil {
 					js.Global().Get("console").Call("log", "Calling panic handler")
-					currentProgram.panicHandler(r)
+					p.panicHandler(r)
 				} else {
-					// Fallback if no current program (shouldn't happen)
-					js.Global().Get("console").Call("log", "No current program - using fallback")
+					// Fallback for renders that are not driven by a program
+					js.Global().Get("console").Call("log", "No program - using fallback")
 					fmt.Printf("Caught panic in render callback:\n\n%s\n\n", r)
 					debug.PrintStack()
 				}
@@ -208,6 +211,12 @@ func requestAnimationFrame(callback func(float64, func(Msg)), send func(Msg)) {
 	global().Call("requestAnimationFrame", cb)
 }
 
+// frameTime returns performance.now(), the clock of requestAnimationFrame's
+// timestamps.
+func frameTime(*Program) float64 {
+	return global().Get("performance").Call("now").Float()
+}
+
 // reconcileProperties updates properties/attributes/etc to match the current
 // element.
 func (h *HTML) reconcileProperties(prev *HTML) {
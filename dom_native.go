@@ -6,6 +6,7 @@ package masc
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	dom "github.com/gost-dom/browser/dom"
 	ev "github.com/gost-dom/browser/dom/event"
@@ -103,15 +104,20 @@ func (e *Event) Call(method string, args ...interface{}) SyscallJSValue {
 	return nil
 }
 
-// gostPerformance implements jsObject for performance.now()
+// gostPerformance implements jsObject for performance.now(). There is no
+// performance timeline to record to, so the marks and measures of a
+// PerformanceTracer are dropped.
 type gostPerformance struct{}
 
 func (p *gostPerformance) Get(string) jsObject     { return nil }
 func (p *gostPerformance) Set(string, interface{}) {}
 func (p *gostPerformance) Delete(string)           {}
 func (p *gostPerformance) Call(name string, _ ...interface{}) jsObject {
-	if name == "now" {
-		return &floatObject{f: 0}
+	switch name {
+	case "now":
+		return &floatObject{f: frameTime(nil)}
+	case "mark", "measure":
+		return nil
 	}
 	panic("gostdom: performance.Call(\"" + name + "\") not implemented")
 }
@@ -145,7 +151,7 @@ func (f *floatObject) Float() float64    { return f.f }
 
 // requestAnimationFrame schedules the callback immediately in the native environment.
 // This simulates the next animation frame for testing purposes.
-func requestAnimationFrame(callback func(float64, func(Msg)), send func(Msg)) {
+func requestAnimationFrame(p *Program, callback func(float64, func(Msg)), send func(Msg)) {
 	// Create JS function wrapper for the callback so that test harness can record it.
 	var cb jsFunc
 	cb = funcOf(func(_ jsObject, args []jsObject) interface{} {
@@ -155,12 +161,25 @@ func requestAnimationFrame(callback func(float64, func(Msg)), send func(Msg)) {
 	})
 	// Schedule via global.Call to allow tests to intercept the rAF invocation.
 	global().Call("requestAnimationFrame", cb)
-	// In non-test environments, immediately invoke the callback to simulate the next frame.
-	if !isTest {
-		callback(0, send)
+	// In a gost-dom window, immediately invoke the callback to simulate the
+	// next frame. Other globals, such as the mocks of the tests, run frames
+	// themselves.
+	if _, ok := global().(*gostGlobal); ok {
+		callback(frameTime(p), send)
 	}
 }
 
+// frameTime returns the current time in milliseconds, as performance.now()
+// would, according to p's Clock. Without a program, the clock set by
+// UseClock is used.
+func frameTime(p *Program) float64 {
+	clock := useClock()
+	if p != nil && p.clock != nil {
+		clock = p.clock
+	}
+	return float64(clock.Now().UnixNano()) / float64(time.Millisecond)
+}
+
 // Node returns the underlying JavaScript Element or TextNode.
 //
 // It panics if it is called before the DOM node has been attached, i.e. before
@@ -175,13 +194,13 @@ func (h *HTML) Node() SyscallJSValue {
 // If the Component's Render method does not return an element of the same type,
 // an error of type ElementMismatchError is returned.
 func RenderIntoNode(node SyscallJSValue, c Component, send func(Msg)) error {
-	return renderIntoNode("RenderIntoNode", node, c, send)
+	return renderIntoNode("RenderIntoNode", node, c, send, newBatchRenderer(nil))
 }
 
 // RenderTo configures the renderer to render the model to the passed DOM node.
 func RenderTo(rootNode SyscallJSValue) ProgramOption {
 	return func(p *Program) {
-		p.renderer = newNodeRenderer(rootNode)
+		p.renderer = newNodeRenderer(p, rootNode)
 	}
 }
 
@@ -189,6 +208,8 @@ func toLower(s string) string {
 	return strings.ToLower(s)
 }
 
+// globalValue is the window set by UseGostDOM. Unlike the rendering state, it
+// is shared by all programs. Tests replace it with a mock.
 var globalValue jsObject
 
 func global() jsObject {
@@ -258,19 +279,31 @@ func (g *gostGlobal) Get(key string) jsObject {
 		return &stringObject{s: "complete"}
 	case "performance":
 		return &gostPerformance{}
+	case "location":
+		return &gostLocation{loc: g.win.Location()}
+	case "innerWidth":
+		return &floatObject{f: gostWindowWidth}
+	case "innerHeight":
+		return &floatObject{f: gostWindowHeight}
 	}
 	panic("gostdom: global.Get(\"" + key + "\") not implemented")
 }
-func (*gostGlobal) Set(_ string, _ interface{})              {}
-func (*gostGlobal) Delete(_ string)                          {}
-func (*gostGlobal) Call(_ string, _ ...interface{}) jsObject { return nil }
-func (*gostGlobal) String() string                           { return "[gostdom global]" }
-func (*gostGlobal) Truthy() bool                             { return true }
-func (g *gostGlobal) Equal(o jsObject) bool                  { return g == o.(*gostGlobal) }
-func (*gostGlobal) IsUndefined() bool                        { return false }
-func (*gostGlobal) Bool() bool                               { return false }
-func (*gostGlobal) Int() int                                 { return 0 }
-func (*gostGlobal) Float() float64                           { return 0 }
+func (*gostGlobal) Set(_ string, _ interface{}) {}
+func (*gostGlobal) Delete(_ string)             {}
+func (g *gostGlobal) Call(name string, args ...interface{}) jsObject {
+	switch name {
+	case "addEventListener", "removeEventListener", "dispatchEvent":
+		return callEventTarget(g.win, name, args)
+	}
+	return nil
+}
+func (*gostGlobal) String() string          { return "[gostdom global]" }
+func (*gostGlobal) Truthy() bool            { return true }
+func (g *gostGlobal) Equal(o jsObject) bool { return g == o.(*gostGlobal) }
+func (*gostGlobal) IsUndefined() bool       { return false }
+func (*gostGlobal) Bool() bool              { return false }
+func (*gostGlobal) Int() int                { return 0 }
+func (*gostGlobal) Float() float64          { return 0 }
 
 // gostWrapper wraps a gost-dom/browser dom.Node and implements jsObject.
 type gostWrapper struct {
@@ -310,6 +343,9 @@ func (g *gostWrapper) Get(key string) jsObject {
 		return &gostWrapper{n: p}
 	case "readyState":
 		return &stringObject{s: "complete"}
+	case "visibilityState":
+		// gost-dom pages are always visible.
+		return &stringObject{s: "visible"}
 	case "value":
 		if el, ok := g.n.(dom.Element); ok {
 			val, _ := el.GetAttribute("value")
@@ -422,32 +458,9 @@ func (g *gostWrapper) Call(name string, args ...interface{}) jsObject {
 			el.RemoveAttribute(key)
 		}
 		return nil
-	case "addEventListener":
-		if el, ok := g.n.(ev.EventTarget); ok {
-			eventType := args[0].(string)
-			if cb, ok2 := args[1].(*gostFunc); ok2 {
-				handler := ev.NewEventHandlerFuncWithoutError(func(evt *ev.Event) {
-					// Wrap the gost-dom Event for user callback
-					ge := &gostEvent{ev: evt}
-					// Invoke callback with the event wrapper
-					cb.goFunc(ge, []jsObject{ge})
-				})
-				el.AddEventListener(eventType, handler)
-			}
-		}
-		return nil
-	case "removeEventListener":
-		if el, ok := g.n.(ev.EventTarget); ok {
-			eventType := args[0].(string)
-			if cb, ok2 := args[1].(*gostFunc); ok2 {
-				handler := ev.NewEventHandlerFuncWithoutError(func(evt *ev.Event) {
-					if tgtNode, ok3 := evt.Target().(dom.Node); ok3 {
-						w := &wrappedObject{j: &gostWrapper{n: tgtNode}}
-						cb.goFunc(w, []jsObject{w})
-					}
-				})
-				el.RemoveEventListener(eventType, handler)
-			}
+	case "addEventListener", "removeEventListener":
+		if tgt, ok := g.n.(ev.EventTarget); ok {
+			return callEventTarget(tgt, name, args)
 		}
 		return nil
 	case "querySelector":
@@ -489,16 +502,34 @@ func (g *gostWrapper) Call(name string, args ...interface{}) jsObject {
 		}
 		return &gostNodeList{list: nil}
 	case "dispatchEvent":
-		// Dispatch a gost-dom Event
 		if tgt, ok := g.n.(ev.EventTarget); ok {
-			if ge, ok2 := args[0].(*gostEvent); ok2 && ge.ev != nil {
-				tgt.DispatchEvent(ge.ev)
-			}
+			return callEventTarget(tgt, name, args)
 		}
 		return nil
 	}
 	panic("gostdom: Call \"" + name + "\" not implemented")
 }
+
+// callEventTarget implements addEventListener, removeEventListener and
+// dispatchEvent for a gost-dom EventTarget.
+func callEventTarget(tgt ev.EventTarget, name string, args []interface{}) jsObject {
+	switch name {
+	case "addEventListener":
+		if cb, ok := args[1].(*gostFunc); ok {
+			tgt.AddEventListener(args[0].(string), cb.handler())
+		}
+	case "removeEventListener":
+		if cb, ok := args[1].(*gostFunc); ok {
+			tgt.RemoveEventListener(args[0].(string), cb.handler())
+		}
+	case "dispatchEvent":
+		// Dispatch a gost-dom Event
+		if ge, ok := args[0].(*gostEvent); ok && ge.ev != nil {
+			tgt.DispatchEvent(ge.ev)
+		}
+	}
+	return nil
+}
 func (g *gostWrapper) String() string { return g.n.NodeName() }
 func (g *gostWrapper) Truthy() bool   { return true }
 func (g *gostWrapper) Equal(o jsObject) bool {
@@ -513,10 +544,64 @@ func (*gostWrapper) Float() float64      { return 0 }
 // gostFunc implements jsFunc for event callbacks (no-op Release).
 type gostFunc struct {
 	goFunc func(this jsObject, args []jsObject) interface{}
+	// h is the event handler wrapping goFunc. It is created once, so that
+	// removeEventListener finds the handler added by addEventListener.
+	h ev.EventHandler
 }
 
 func (*gostFunc) Release() {}
 
+func (f *gostFunc) handler() ev.EventHandler {
+	if f.h == nil {
+		f.h = ev.NewEventHandlerFuncWithoutError(func(evt *ev.Event) {
+			// Wrap the gost-dom Event for user callback
+			ge := &gostEvent{ev: evt}
+			// Invoke callback with the event wrapper
+			f.goFunc(ge, []jsObject{ge})
+		})
+	}
+	return f.h
+}
+
+// The inner size of the window reported under gost-dom, which does not do
+// layout.
+const (
+	gostWindowWidth  = 1024
+	gostWindowHeight = 768
+)
+
+// gostLocation implements jsObject for window.location.
+type gostLocation struct {
+	loc html.Location
+}
+
+func (l *gostLocation) Get(key string) jsObject {
+	switch key {
+	case "href":
+		return &stringObject{s: l.loc.Href()}
+	case "hash":
+		return &stringObject{s: l.loc.Hash()}
+	case "pathname":
+		return &stringObject{s: l.loc.Pathname()}
+	case "search":
+		return &stringObject{s: l.loc.Search()}
+	}
+	panic("gostdom: location.Get(\"" + key + "\") not implemented")
+}
+func (*gostLocation) Set(string, interface{})              {}
+func (*gostLocation) Delete(string)                        {}
+func (*gostLocation) Call(string, ...interface{}) jsObject { return nil }
+func (l *gostLocation) String() string                     { return l.loc.Href() }
+func (*gostLocation) Truthy() bool                         { return true }
+func (l *gostLocation) Equal(o jsObject) bool {
+	other, ok := o.(*gostLocation)
+	return ok && l.loc == other.loc
+}
+func (*gostLocation) IsUndefined() bool { return false }
+func (*gostLocation) Bool() bool        { return true }
+func (*gostLocation) Int() int          { return 0 }
+func (*gostLocation) Float() float64    { return 0 }
+
 // stringObject wraps a Go string as a jsObject.
 type stringObject struct{ s string }
 
@@ -705,6 +790,13 @@ func (e *gostEvent) Set(key string, value interface{}) {}
 func (e *gostEvent) Delete(key string)                 {}
 
 func (e *gostEvent) Get(key string) jsObject {
+	// Event properties can be supplied in the event's Data, for example
+	// map[string]interface{}{"key": "s", "ctrlKey": true}.
+	if data, ok := e.ev.Data.(map[string]interface{}); ok {
+		if v, ok := data[key]; ok {
+			return gostValue(v)
+		}
+	}
 	switch key {
 	case "value":
 		if el, ok := e.ev.Target().(dom.Element); ok {
@@ -723,10 +815,34 @@ func (e *gostEvent) Get(key string) jsObject {
 	case "code":
 		// Stub for keyboard events in native build - will be properly handled in JS/WASM
 		return &stringObject{s: ""}
+	case "type":
+		return &stringObject{s: e.ev.Type}
+	case "ctrlKey", "altKey", "shiftKey", "metaKey":
+		return gostValue(false)
+	case "oldURL", "newURL":
+		return gostValue("")
 	}
 	return nil
 }
 
+// gostValue converts a Go string, bool or number to a jsObject.
+func gostValue(v interface{}) jsObject {
+	switch v := v.(type) {
+	case string:
+		return &stringObject{s: v}
+	case bool:
+		if v {
+			return &floatObject{f: 1}
+		}
+		return &floatObject{f: 0}
+	case int:
+		return &floatObject{f: float64(v)}
+	case float64:
+		return &floatObject{f: v}
+	}
+	panic(fmt.Sprintf("gostdom: unsupported event property value %T", v))
+}
+
 func (e *gostEvent) Call(name string, _ ...interface{}) jsObject {
 	switch name {
 	case "preventDefault":
@@ -11,7 +11,7 @@ import (
 
 func init() {
 	// skip browser guard when running under `go test` or other test binaries
-	if isTest || flag.Lookup("test.v") != nil || (len(os.Args) > 0 && (strings.HasSuffix(os.Args[0], ".test") || strings.HasSuffix(os.Args[0], ".test.exe"))) {
+	if flag.Lookup("test.v") != nil || (len(os.Args) > 0 && (strings.HasSuffix(os.Args[0], ".test") || strings.HasSuffix(os.Args[0], ".test.exe"))) {
 		return
 	}
 	if global() == nil {
@@ -2,6 +2,7 @@ package masc
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -94,20 +95,20 @@ func TestHTML_reconcile_std(t *testing.T) {
 		defer ts.done()
 
 		init := Text("foobar")
-		init.reconcile(nil, send)
+		init.reconcile(nil, send, nil)
 
 		target := Text("foobar")
-		target.reconcile(init, send)
+		target.reconcile(init, send, nil)
 	})
 	t.Run("text_diff", func(t *testing.T) {
 		ts := testSuite(t)
 		defer ts.done()
 
 		init := Text("bar")
-		init.reconcile(nil, send)
+		init.reconcile(nil, send, nil)
 
 		target := Text("foo")
-		target.reconcile(init, send)
+		target.reconcile(init, send, nil)
 	})
 	t.Run("properties", func(t *testing.T) {
 		cases := []struct {
@@ -146,9 +147,9 @@ func TestHTML_reconcile_std(t *testing.T) {
 				ts := testSuite(t)
 				defer ts.multiSortedDone(tst.sortedLines...)
 
-				tst.initHTML.reconcile(nil, send)
+				tst.initHTML.reconcile(nil, send, nil)
 				ts.record("(first reconcile done)")
-				tst.targetHTML.reconcile(tst.initHTML, send)
+				tst.targetHTML.reconcile(tst.initHTML, send, nil)
 			})
 		}
 	})
@@ -177,9 +178,9 @@ func TestHTML_reconcile_std(t *testing.T) {
 				ts := testSuite(t)
 				defer ts.multiSortedDone(tst.sortedLines...)
 
-				tst.initHTML.reconcile(nil, send)
+				tst.initHTML.reconcile(nil, send, nil)
 				ts.record("(first reconcile done)")
-				tst.targetHTML.reconcile(tst.initHTML, send)
+				tst.targetHTML.reconcile(tst.initHTML, send, nil)
 			})
 		}
 	})
@@ -232,9 +233,9 @@ func TestHTML_reconcile_std(t *testing.T) {
 				ts := testSuite(t)
 				defer ts.multiSortedDone(tst.sortedLines...)
 
-				tst.initHTML.reconcile(nil, send)
+				tst.initHTML.reconcile(nil, send, nil)
 				ts.record("(first reconcile done)")
-				tst.targetHTML.reconcile(tst.initHTML, send)
+				tst.targetHTML.reconcile(tst.initHTML, send, nil)
 			})
 		}
 	})
@@ -263,9 +264,9 @@ func TestHTML_reconcile_std(t *testing.T) {
 				ts := testSuite(t)
 				defer ts.multiSortedDone(tst.sortedLines...)
 
-				tst.initHTML.reconcile(nil, send)
+				tst.initHTML.reconcile(nil, send, nil)
 				ts.record("(first reconcile done)")
-				tst.targetHTML.reconcile(tst.initHTML, send)
+				tst.targetHTML.reconcile(tst.initHTML, send, nil)
 			})
 		}
 	})
@@ -294,9 +295,9 @@ func TestHTML_reconcile_std(t *testing.T) {
 				ts := testSuite(t)
 				defer ts.multiSortedDone(tst.sortedLines...)
 
-				tst.initHTML.reconcile(nil, send)
+				tst.initHTML.reconcile(nil, send, nil)
 				ts.record("(first reconcile done)")
-				tst.targetHTML.reconcile(tst.initHTML, send)
+				tst.targetHTML.reconcile(tst.initHTML, send, nil)
 			})
 		}
 	})
@@ -310,7 +311,7 @@ func TestHTML_reconcile_std(t *testing.T) {
 			&EventListener{Name: "keydown"},
 		}
 		prev := Tag("div", Markup(initEventListeners...))
-		prev.reconcile(nil, send)
+		prev.reconcile(nil, send, nil)
 		ts.record("(expected two added event listeners above)")
 		for i, m := range initEventListeners {
 			listener := m.(*EventListener)
@@ -323,7 +324,7 @@ func TestHTML_reconcile_std(t *testing.T) {
 			&EventListener{Name: "click"},
 		}
 		h := Tag("div", Markup(targetEventListeners...))
-		h.reconcile(prev, send)
+		h.reconcile(prev, send, nil)
 		ts.record("(expected two removed, one added event listeners above)")
 		for i, m := range targetEventListeners {
 			listener := m.(*EventListener)
@@ -343,7 +344,7 @@ func TestHTML_reconcile_nil(t *testing.T) {
 	t.Run("one_of_tag_or_text", func(t *testing.T) {
 		got := recoverStr(func() {
 			h := &HTML{text: "hello", tag: "div"}
-			h.reconcile(nil, send)
+			h.reconcile(nil, send, nil)
 		})
 		want := "masc: internal error (only one of HTML.tag or HTML.text may be set)"
 		if got != want {
@@ -353,7 +354,7 @@ func TestHTML_reconcile_nil(t *testing.T) {
 	t.Run("unsafe_text", func(t *testing.T) {
 		got := recoverStr(func() {
 			h := &HTML{text: "hello", innerHTML: "foobar"}
-			h.reconcile(nil, send)
+			h.reconcile(nil, send, nil)
 		})
 		want := "masc: only HTML may have UnsafeHTML attribute"
 		if got != want {
@@ -365,56 +366,56 @@ func TestHTML_reconcile_nil(t *testing.T) {
 		defer ts.done()
 
 		h := Tag("strong")
-		h.reconcile(nil, send)
+		h.reconcile(nil, send, nil)
 	})
 	t.Run("create_element_ns", func(t *testing.T) {
 		ts := testSuite(t)
 		defer ts.done()
 
 		h := Tag("strong", Markup(Namespace("foobar")))
-		h.reconcile(nil, send)
+		h.reconcile(nil, send, nil)
 	})
 	t.Run("create_text_node", func(t *testing.T) {
 		ts := testSuite(t)
 		defer ts.done()
 
 		h := Text("hello")
-		h.reconcile(nil, send)
+		h.reconcile(nil, send, nil)
 	})
 	t.Run("inner_html", func(t *testing.T) {
 		ts := testSuite(t)
 		defer ts.done()
 
 		h := Tag("div", Markup(UnsafeHTML("<p>hello</p>")))
-		h.reconcile(nil, send)
+		h.reconcile(nil, send, nil)
 	})
 	t.Run("properties", func(t *testing.T) {
 		ts := testSuite(t)
 		defer ts.sortedDone(3, 4)
 
 		h := Tag("div", Markup(Property("a", 1), Property("b", "2foobar")))
-		h.reconcile(nil, send)
+		h.reconcile(nil, send, nil)
 	})
 	t.Run("attributes", func(t *testing.T) {
 		ts := testSuite(t)
 		defer ts.sortedDone(3, 4)
 
 		h := Tag("div", Markup(Attribute("a", 1), Attribute("b", "2foobar")))
-		h.reconcile(nil, send)
+		h.reconcile(nil, send, nil)
 	})
 	t.Run("dataset", func(t *testing.T) {
 		ts := testSuite(t)
 		defer ts.sortedDone(5, 6)
 
 		h := Tag("div", Markup(Data("a", "1"), Data("b", "2foobar")))
-		h.reconcile(nil, send)
+		h.reconcile(nil, send, nil)
 	})
 	t.Run("style", func(t *testing.T) {
 		ts := testSuite(t)
 		defer ts.sortedDone(6, 7)
 
 		h := Tag("div", Markup(Style("a", "1"), Style("b", "2foobar")))
-		h.reconcile(nil, send)
+		h.reconcile(nil, send, nil)
 	})
 	t.Run("add_event_listener", func(t *testing.T) {
 		ts := testSuite(t)
@@ -423,7 +424,7 @@ func TestHTML_reconcile_nil(t *testing.T) {
 		e0 := &EventListener{Name: "click"}
 		e1 := &EventListener{Name: "keydown"}
 		h := Tag("div", Markup(e0, e1))
-		h.reconcile(nil, send)
+		h.reconcile(nil, send, nil)
 		if e0.wrapper == nil {
 			t.Fatal("e0.wrapper == nil")
 		}
@@ -445,7 +446,7 @@ func TestHTML_reconcile_nil(t *testing.T) {
 			},
 		}
 		h := Tag("div", Tag("div", comp))
-		h.reconcile(nil, send)
+		h.reconcile(nil, send, nil)
 		if compRenderCalls != 1 {
 			t.Fatal("compRenderCalls != 1")
 		}
@@ -469,7 +470,7 @@ func TestHTML_reconcile_nil(t *testing.T) {
 			},
 		}
 		h := Tag("div", Tag("div", comp))
-		h.reconcile(nil, send)
+		h.reconcile(nil, send, nil)
 		if compRenderCalls != 1 {
 			t.Fatal("compRenderCalls != 1")
 		}
@@ -527,7 +528,7 @@ func TestRerender_nil(t *testing.T) {
 				gotPanic = fmt.Sprint(r)
 			}
 		}()
-		rerender(nil, send)
+		rerender(newBatchRenderer(nil), nil, send)
 	}()
 	expected := "masc: Rerender illegally called with a nil Component argument"
 	if gotPanic != expected {
@@ -542,7 +543,7 @@ func TestRerender_no_prevRender(t *testing.T) {
 	defer ts.done()
 
 	got := recoverStr(func() {
-		rerender(&componentFunc{
+		rerender(newBatchRenderer(nil), &componentFunc{
 			render: func() ComponentOrHTML {
 				panic("expected no Render call")
 			},
@@ -578,7 +579,8 @@ func TestRerender_identical(t *testing.T) {
 			return render
 		},
 	}
-	RenderBody(comp, send)
+	batch := newBatchRenderer(nil)
+	renderBody(batch, comp, send)
 	if renderCalled != 1 {
 		t.Fatal("renderCalled != 1")
 	}
@@ -609,7 +611,7 @@ func TestRerender_identical(t *testing.T) {
 		skipRenderCalled++
 		return false
 	}
-	rerender(comp, send)
+	rerender(batch, comp, send)
 
 	// Invoke the render callback.
 	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
@@ -667,7 +669,8 @@ func TestRerender_change(t *testing.T) {
 					return render
 				},
 			}
-			RenderBody(comp, send)
+			batch := newBatchRenderer(nil)
+			renderBody(batch, comp, send)
 			ts.record("(expect body to be set now)")
 			if renderCalled != 1 {
 				t.Fatal("renderCalled != 1")
@@ -698,7 +701,7 @@ func TestRerender_change(t *testing.T) {
 				skipRenderCalled++
 				return false
 			}
-			rerender(comp, send)
+			rerender(batch, comp, send)
 
 			// Invoke the render callback.
 			ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
@@ -771,7 +774,8 @@ func TestRerender_Nested(t *testing.T) {
 					return tst.initialRender
 				},
 			}
-			RenderBody(comp, send)
+			batch := newBatchRenderer(nil)
+			renderBody(batch, comp, send)
 			ts.record("(expect body to be set now)")
 			if renderCalled != 1 {
 				t.Fatal("renderCalled != 1")
@@ -802,7 +806,7 @@ func TestRerender_Nested(t *testing.T) {
 				skipRenderCalled++
 				return false
 			}
-			rerender(comp, send)
+			rerender(batch, comp, send)
 
 			// Invoke the render callback.
 			ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
@@ -867,14 +871,15 @@ func TestRerender_persistent(t *testing.T) {
 
 	comp := &persistentComponentBody{}
 	// Perform the initial render of the component.
-	RenderBody(comp, send)
+	batch := newBatchRenderer(nil)
+	renderBody(batch, comp, send)
 
 	if renderCount != 1 {
 		t.Fatal("renderCount != 1")
 	}
 
 	// Perform a re-render.
-	rerender(comp, send)
+	rerender(batch, comp, send)
 
 	// Invoke the render callback.
 	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
@@ -885,7 +890,7 @@ func TestRerender_persistent(t *testing.T) {
 	}
 
 	// Perform a re-render.
-	rerender(comp, send)
+	rerender(batch, comp, send)
 
 	// Invoke the render callback.
 	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
@@ -931,14 +936,15 @@ func TestRerender_persistent_direct(t *testing.T) {
 
 	comp := &persistentComponentBody2{}
 	// Perform the initial render of the component.
-	RenderBody(comp, send)
+	batch := newBatchRenderer(nil)
+	renderBody(batch, comp, send)
 
 	if renderCount != 1 {
 		t.Fatal("renderCount != 1")
 	}
 
 	// Perform a re-render.
-	rerender(comp, send)
+	rerender(batch, comp, send)
 
 	// Invoke the render callback.
 	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
@@ -949,7 +955,7 @@ func TestRerender_persistent_direct(t *testing.T) {
 	}
 
 	// Perform a re-render.
-	rerender(comp, send)
+	rerender(batch, comp, send)
 
 	// Invoke the render callback.
 	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
@@ -1148,10 +1154,11 @@ func TestKeyedChild_DifferentType(t *testing.T) {
 		skipRender: func(prev Component) bool { return false },
 	}
 
-	RenderBody(comp, send)
+	batch := newBatchRenderer(nil)
+	renderBody(batch, comp, send)
 
 	rerender := func() {
-		rerender(comp, send)
+		rerender(batch, comp, send)
 		ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
 		ts.invokeCallbackRequestAnimationFrame(0)
 	}
@@ -1172,6 +1179,56 @@ func TestKeyedChild_DifferentType(t *testing.T) {
 	rerender()
 }
 
+// TestKeyedChild_Moves tests that reordering keyed children moves the fewest
+// DOM nodes.
+func TestKeyedChild_Moves(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.strings.mock(`global.Get("document").Get("readyState")`, "complete")
+	ts.strings.mock(`global.Get("document").Call("querySelector", "body").Get("nodeName")`, "BODY")
+	ts.truthies.mock(`global.Get("document").Call("querySelector", "body")`, true)
+
+	// Each child is a tag named after its key, so that the moves can be told
+	// apart.
+	order := "abcde"
+	comp := &componentFunc{
+		render: func() ComponentOrHTML {
+			var children []MarkupOrChild
+			for _, k := range strings.Split(order, "") {
+				children = append(children, Tag(k, Markup(ElementKey(k))))
+			}
+			return Tag("body", Tag("ul", children...))
+		},
+		skipRender: func(prev Component) bool { return false },
+	}
+
+	batch := newBatchRenderer(nil)
+	renderBody(batch, comp, send)
+
+	for _, tt := range []struct {
+		order string
+		moves int
+	}{
+		{order: "edcba", moves: 4}, // reverse
+		{order: "dcbae", moves: 1}, // rotate
+		{order: "adcbe", moves: 1},
+		{order: "abcde", moves: 2},
+		{order: "abcde", moves: 0},
+	} {
+		order = tt.order
+		ts.record("// " + order)
+		start := len(ts.got)
+		rerender(batch, comp, send)
+		ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+		ts.invokeCallbackRequestAnimationFrame(0)
+		if moves := strings.Count(ts.got[start:], `.Call("insertBefore"`); moves != tt.moves {
+			t.Errorf("%s: got %d moves, want %d", order, moves, tt.moves)
+		}
+	}
+}
+
 // TestEventListenerRelease_TagChange tests that event listener wrappers are
 // released when a node's tag changes during reconciliation.
 func TestEventListenerRelease_TagChange(t *testing.T) {
@@ -1182,7 +1239,7 @@ func TestEventListenerRelease_TagChange(t *testing.T) {
 	e0 := &EventListener{Name: "click"}
 	e1 := &EventListener{Name: "keydown"}
 	prev := Tag("div", Markup(e0, e1))
-	prev.reconcile(nil, send)
+	prev.reconcile(nil, send, nil)
 
 	// Verify wrappers were created
 	if e0.wrapper == nil {
@@ -1205,7 +1262,7 @@ func TestEventListenerRelease_TagChange(t *testing.T) {
 
 	// Reconcile with a different tag - this should release old event listeners
 	next := Tag("span") // Different tag, no event listeners
-	next.reconcile(prev, send)
+	next.reconcile(prev, send, nil)
 
 	// Verify old wrappers were released
 	if !w0.released {
@@ -1225,7 +1282,7 @@ func TestEventListenerRelease_Unmount(t *testing.T) {
 	// Create element with event listeners
 	e0 := &EventListener{Name: "click"}
 	h := Tag("div", Markup(e0))
-	h.reconcile(nil, send)
+	h.reconcile(nil, send, nil)
 
 	// Verify wrapper was created
 	if e0.wrapper == nil {
@@ -1256,7 +1313,7 @@ func TestEventListenerRelease_NestedUnmount(t *testing.T) {
 	childListener := &EventListener{Name: "click"}
 	child := Tag("button", Markup(childListener))
 	parent := Tag("div", child)
-	parent.reconcile(nil, send)
+	parent.reconcile(nil, send, nil)
 
 	// Verify wrapper was created
 	if childListener.wrapper == nil {
@@ -1362,7 +1419,7 @@ func TestEventListenerWrapper_NilAfterRelease(t *testing.T) {
 	// Create element with event listener
 	e0 := &EventListener{Name: "click"}
 	prev := Tag("div", Markup(e0))
-	prev.reconcile(nil, send)
+	prev.reconcile(nil, send, nil)
 
 	// Verify wrapper was created
 	if e0.wrapper == nil {
@@ -1371,7 +1428,7 @@ func TestEventListenerWrapper_NilAfterRelease(t *testing.T) {
 
 	// Reconcile with element that has no listeners (triggers removeProperties)
 	next := Tag("div") // Same tag, no event listeners
-	next.reconcile(prev, send)
+	next.reconcile(prev, send, nil)
 
 	// Verify wrapper was released and nilled
 	if e0.wrapper != nil {
@@ -1388,7 +1445,7 @@ func TestReleaseEventListeners_NilsWrapper(t *testing.T) {
 	// Create element with event listener
 	e0 := &EventListener{Name: "click"}
 	h := Tag("div", Markup(e0))
-	h.reconcile(nil, send)
+	h.reconcile(nil, send, nil)
 
 	// Verify wrapper was created
 	if e0.wrapper == nil {
@@ -1,13 +1,18 @@
 package example
 
 import (
+	"context"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gost-dom/browser/dom"
+	ev "github.com/gost-dom/browser/dom/event"
 	html "github.com/gost-dom/browser/html"
 	"github.com/octoberswimmer/masc"
 	"github.com/octoberswimmer/masc/elem"
 	"github.com/octoberswimmer/masc/event"
+	"github.com/octoberswimmer/masc/masctest"
 )
 
 // Define a component that tracks mount/unmount calls.
@@ -212,6 +217,107 @@ func TestCmdMsgWorkflow(t *testing.T) {
 	}
 }
 
+// loadComp loads items with the commands returned by Init and Update.
+type loadComp struct {
+	masc.Core
+	items []string
+}
+
+func load(item string) masc.Cmd {
+	return func() masc.Msg { return item }
+}
+
+func (c *loadComp) Init() masc.Cmd {
+	return masc.Sequence(load("a"), load("b"))
+}
+
+func (c *loadComp) Update(msg masc.Msg) (masc.Model, masc.Cmd) {
+	switch msg := msg.(type) {
+	case string:
+		c.items = append(c.items, msg)
+	case loadMore:
+		return c, masc.Batch(load("c"), masc.ContextCmd(func(context.Context) masc.Msg { return "d" }))
+	}
+	return c, nil
+}
+
+func (c *loadComp) Render(send func(masc.Msg)) masc.ComponentOrHTML {
+	return elem.Body(elem.Div(masc.Markup(masc.Property("data-items", strings.Join(c.items, ",")))))
+}
+
+type loadMore struct{}
+
+// TestRenderComponentIntoCmds tests that the gost-dom helpers run the commands
+// returned by Init and Update.
+func TestRenderComponentIntoCmds(t *testing.T) {
+	win, err := html.NewWindowReader(strings.NewReader("<!DOCTYPE html><html><body></body></html>"))
+	if err != nil {
+		t.Fatalf("failed to create gost-dom window: %v", err)
+	}
+	body, send, err := masc.RenderComponentIntoWithSend(win, &loadComp{})
+	if err != nil {
+		t.Fatalf("initial render error: %v", err)
+	}
+	if got := body.InnerHTML(); !strings.Contains(got, `data-items="a,b"`) {
+		t.Errorf("expected the Init commands to run, got %q", got)
+	}
+	send(loadMore{})
+	if got := body.InnerHTML(); !strings.Contains(got, `data-items="a,b,c,d"`) {
+		t.Errorf("expected the Update commands to run, got %q", got)
+	}
+}
+
+// throttleComp loads two items with throttled commands returned by Init.
+type throttleComp struct {
+	masc.Core
+	items []string
+}
+
+func (c *throttleComp) Init() masc.Cmd {
+	return masc.Sequence(
+		masc.Throttle("load", time.Second, load("a")),
+		masc.Throttle("load", time.Second, load("b")),
+	)
+}
+
+func (c *throttleComp) Update(msg masc.Msg) (masc.Model, masc.Cmd) {
+	if msg, ok := msg.(string); ok {
+		c.items = append(c.items, msg)
+	}
+	return c, nil
+}
+
+func (c *throttleComp) Render(send func(masc.Msg)) masc.ComponentOrHTML {
+	return elem.Body(elem.Div(masc.Markup(masc.Property("data-items", strings.Join(c.items, ",")))))
+}
+
+// TestRenderComponentIntoThrottle tests that the gost-dom helpers run commands
+// with the clock set with UseClock, and share throttled keys between them.
+func TestRenderComponentIntoThrottle(t *testing.T) {
+	clock := masctest.NewFakeClock(masctest.Epoch)
+	masc.UseClock(clock)
+	defer masc.UseClock(nil)
+	go func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}()
+
+	win, err := html.NewWindowReader(strings.NewReader("<!DOCTYPE html><html><body></body></html>"))
+	if err != nil {
+		t.Fatalf("failed to create gost-dom window: %v", err)
+	}
+	body, err := masc.RenderComponentInto(win, &throttleComp{})
+	if err != nil {
+		t.Fatalf("initial render error: %v", err)
+	}
+	if got := body.InnerHTML(); !strings.Contains(got, `data-items="a,b"`) {
+		t.Errorf("expected both loads to run, got %q", got)
+	}
+	if want := masctest.Epoch.Add(time.Second); !clock.Now().Equal(want) {
+		t.Errorf("expected the second load to wait until %v, got %v", want, clock.Now())
+	}
+}
+
 // Implement lifecycle methods.
 func (l *lifeComp) Init() masc.Cmd                             { return nil }
 func (l *lifeComp) Update(msg masc.Msg) (masc.Model, masc.Cmd) { return l, nil }
@@ -379,3 +485,145 @@ func TestSkipRender(t *testing.T) {
 		t.Errorf("SkipRender failed: expected data-count=0, got %q", val)
 	}
 }
+
+// emptyBody renders an empty <body>.
+type emptyBody struct{ masc.Core }
+
+func (b *emptyBody) Init() masc.Cmd                                  { return nil }
+func (b *emptyBody) Update(msg masc.Msg) (masc.Model, masc.Cmd)      { return b, nil }
+func (b *emptyBody) Render(send func(masc.Msg)) masc.ComponentOrHTML { return elem.Body() }
+
+// listenedWindow wraps a gost-dom window to report, on added, the type of
+// each event listener added to the window or its document.
+type listenedWindow struct {
+	html.Window
+	document *listenedDocument
+	added    chan string
+}
+
+func newListenedWindow(win html.Window) *listenedWindow {
+	added := make(chan string, 10)
+	return &listenedWindow{
+		Window:   win,
+		document: &listenedDocument{Document: win.Document(), added: added},
+		added:    added,
+	}
+}
+
+func (w *listenedWindow) Document() dom.Document { return w.document }
+
+func (w *listenedWindow) AddEventListener(eventType string, listener ev.EventHandler, options ...func(*ev.EventListener)) {
+	w.Window.AddEventListener(eventType, listener, options...)
+	w.added <- eventType
+}
+
+// listenedDocument is the document of a listenedWindow.
+type listenedDocument struct {
+	dom.Document
+	added chan string
+}
+
+func (d *listenedDocument) AddEventListener(eventType string, listener ev.EventHandler, options ...func(*ev.EventListener)) {
+	d.Document.AddEventListener(eventType, listener, options...)
+	d.added <- eventType
+}
+
+// TestGlobalEventSubscriptions tests window and document event subscriptions
+// under gost-dom.
+func TestGlobalEventSubscriptions(t *testing.T) {
+	win, err := html.NewWindowReader(strings.NewReader("<!DOCTYPE html><html><body></body></html>"))
+	if err != nil {
+		t.Fatalf("failed to create gost-dom window: %v", err)
+	}
+	listened := newListenedWindow(win)
+	body, err := masc.RenderComponentInto(listened, &emptyBody{})
+	if err != nil {
+		t.Fatalf("RenderComponentInto error: %v", err)
+	}
+
+	msgs := make(chan masc.Msg, 10)
+	var stops []func()
+	// Start the subscriptions one at a time, waiting for each to add its
+	// listeners, as gost-dom does not support changing them concurrently.
+	for _, sub := range []struct {
+		sub        masc.Sub
+		eventTypes []string
+	}{
+		{masc.WindowResize(), []string{"resize"}},
+		{masc.OnlineStatus(), []string{"online", "offline"}},
+		{masc.HashChange(), []string{"hashchange"}},
+		{masc.KeyCombos("ctrl+s"), []string{"keydown"}},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func(sub masc.Sub) {
+			defer close(done)
+			sub.Run(ctx, func(msg masc.Msg) { msgs <- msg })
+		}(sub.sub)
+		stops = append(stops, func() {
+			cancel()
+			<-done
+		})
+		for _, eventType := range sub.eventTypes {
+			select {
+			case added := <-listened.added:
+				if added != eventType {
+					t.Fatalf("expected a %s listener, got %s", eventType, added)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("timed out waiting for a %s listener", eventType)
+			}
+		}
+	}
+
+	expect := func(want masc.Msg) {
+		t.Helper()
+		select {
+		case got := <-msgs:
+			if got != want {
+				t.Fatalf("expected %#v, got %#v", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %#v", want)
+		}
+	}
+
+	body.DispatchWindow("resize", nil)
+	expect(masc.ResizeMsg{Width: 1024, Height: 768})
+	body.DispatchWindow("offline", nil)
+	expect(masc.OnlineMsg{Online: false})
+	body.DispatchWindow("hashchange", map[string]interface{}{"oldURL": "/#a", "newURL": "/#b"})
+	expect(masc.HashChangeMsg{OldURL: "/#a", NewURL: "/#b"})
+	body.DispatchDocument("keydown", map[string]interface{}{"key": "s"})
+	body.DispatchDocument("keydown", map[string]interface{}{"key": "S", "ctrlKey": true})
+	expect(masc.KeyComboMsg{Combo: "ctrl+s"})
+
+	// Cancelled subscriptions remove their listeners.
+	for _, stop := range stops {
+		stop()
+	}
+	body.DispatchWindow("online", nil)
+	select {
+	case msg := <-msgs:
+		t.Fatalf("unexpected message after cancellation: %#v", msg)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// TestPerformanceTracer tests that the PerformanceTracer can record to the
+// performance object of a gost-dom window, which has no timeline.
+func TestPerformanceTracer(t *testing.T) {
+	win, err := html.NewWindowReader(strings.NewReader("<!DOCTYPE html><html><body></body></html>"))
+	if err != nil {
+		t.Fatalf("failed to create gost-dom window: %v", err)
+	}
+	masc.UseGostDOM(win)
+
+	var tracer masc.PerformanceTracer
+	tracer.MsgReceived(struct{}{})
+	tracer.Update(struct{}{}, time.Millisecond)
+	tracer.Render(&emptyBody{}, time.Millisecond, time.Millisecond)
+	tracer.CmdStart(1)
+	tracer.CmdFinish(1, struct{}{}, time.Millisecond)
+	tracer.FrameOverrun(20*time.Millisecond, 1)
+}
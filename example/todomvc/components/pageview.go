@@ -1,10 +1,8 @@
 package components
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
-	"syscall/js"
 
 	"github.com/octoberswimmer/masc"
 	"github.com/octoberswimmer/masc/elem"
@@ -32,8 +30,6 @@ type Item struct {
 	editTitle string
 }
 
-type ItemList []*Item
-
 // FilterState represents a viewing filter for Todo items in the store.
 type FilterState int
 
@@ -53,21 +49,14 @@ func (m *PageView) Init() masc.Cmd {
 	masc.AddStylesheet("https://rawgit.com/tastejs/todomvc-common/master/base.css")
 	masc.AddStylesheet("https://rawgit.com/tastejs/todomvc-app-css/master/index.css")
 
-	return attachLocalStorage
+	return nil
 }
 
 func (m *PageView) Update(msg masc.Msg) (masc.Model, masc.Cmd) {
-	var (
-		cmds []masc.Cmd
-	)
 	switch msg := msg.(type) {
-	case ItemList:
-		m.Items = msg
-		return m, nil
 	case AddItemMsg:
 		m.Items = append(m.Items, &Item{Title: m.newItemTitle})
 		m.newItemTitle = ""
-		cmds = append(cmds, m.updateLocalStorage)
 	case NewItemTitleMsg:
 		m.newItemTitle = msg.Title
 	case ClearCompleted:
@@ -78,12 +67,10 @@ func (m *PageView) Update(msg masc.Msg) (masc.Model, masc.Cmd) {
 			}
 		}
 		m.Items = activeItems
-		cmds = append(cmds, m.updateLocalStorage)
 	case SetAllCompleted:
 		for _, item := range m.Items {
 			item.Completed = msg.Completed
 		}
-		cmds = append(cmds, m.updateLocalStorage)
 	case SetFilter:
 		m.Filter = msg.Filter
 	case StartEdit:
@@ -95,38 +82,12 @@ func (m *PageView) Update(msg masc.Msg) (masc.Model, masc.Cmd) {
 		m.Items[msg.Index].editing = false
 		m.Items[msg.Index].Title = m.Items[msg.Index].editTitle
 		m.Items[msg.Index].editTitle = ""
-		cmds = append(cmds, m.updateLocalStorage)
 	case UpdateCompleted:
 		m.Items[msg.Index].Completed = msg.Completed
-		cmds = append(cmds, m.updateLocalStorage)
 	case Destroy:
 		m.Items = append(m.Items[:msg.Index], m.Items[msg.Index+1:]...)
-		cmds = append(cmds, m.updateLocalStorage)
-	}
-	return m, masc.Batch(cmds...)
-}
-
-func (m *PageView) updateLocalStorage() masc.Msg {
-	fmt.Printf("Marshalling %+v", m.Items)
-	data, err := json.Marshal(m.Items)
-	if err != nil {
-		fmt.Println("failed to store items: " + err.Error())
 	}
-	js.Global().Get("localStorage").Set("items", string(data))
-	fmt.Println("Updated local storage", string(data))
-	return nil
-}
-
-func attachLocalStorage() masc.Msg {
-	if data := js.Global().Get("localStorage").Get("items"); !data.IsUndefined() {
-		fmt.Println("Got items from localStorage", data.String())
-		var items ItemList
-		if err := json.Unmarshal([]byte(data.String()), &items); err != nil {
-			println("failed to load items: " + err.Error())
-		}
-		return items
-	}
-	return nil
+	return m, nil
 }
 
 func (m *PageView) onNewItemTitleInput(send func(masc.Msg)) func(*masc.Event) {
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+
 	"github.com/octoberswimmer/masc"
 	"github.com/octoberswimmer/masc/elem"
 
@@ -12,9 +14,9 @@ type FocusCheck struct{}
 func main() {
 	masc.SetTitle("Hello masc!")
 	m := &Body{
-		todo: &components.PageView{},
+		todo: masc.NewChild[todoMsg](&components.PageView{}),
 	}
-	pgm := masc.NewProgram(m)
+	pgm := masc.NewProgram(m, masc.WithPersistence(masc.LocalStorage("items"), itemsCodec{}))
 
 	_, err := pgm.Run()
 	if err != nil {
@@ -23,9 +25,12 @@ func main() {
 
 }
 
+// todoMsg tags the messages of the todo list.
+type todoMsg struct{ Msg masc.Msg }
+
 type Body struct {
 	masc.Core
-	todo *components.PageView
+	todo *masc.Child[todoMsg, *components.PageView]
 }
 
 func (b *Body) Init() masc.Cmd {
@@ -33,13 +38,29 @@ func (b *Body) Init() masc.Cmd {
 }
 
 func (b *Body) Update(msg masc.Msg) (masc.Model, masc.Cmd) {
-	p, cmd := b.todo.Update(msg)
-	b.todo = p.(*components.PageView)
-	return b, cmd
+	if cmd, ok := b.todo.Update(msg); ok {
+		return b, cmd
+	}
+	return b, nil
 }
 
 func (b *Body) Render(send func(masc.Msg)) masc.ComponentOrHTML {
 	return elem.Body(
-		b.todo,
+		b.todo.View(),
 	)
 }
+
+// itemsCodec persists the todo items of a Body.
+type itemsCodec struct{}
+
+func (itemsCodec) Marshal(m masc.Model) ([]byte, error) {
+	return json.Marshal(m.(*Body).todo.Model.Items)
+}
+
+func (itemsCodec) Unmarshal(data []byte, m masc.Model) (masc.Model, error) {
+	b := m.(*Body)
+	if err := json.Unmarshal(data, &b.todo.Model.Items); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
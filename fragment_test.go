@@ -0,0 +1,137 @@
+package masc
+
+import (
+	"fmt"
+	"testing"
+)
+
+// rowsComponent renders its Rows as a fragment, or a single element if
+// Single is set.
+type rowsComponent struct {
+	Core
+	Rows      []string `masc:"prop"`
+	Single    bool     `masc:"prop"`
+	unmounted *int
+}
+
+func (c *rowsComponent) Render(send func(Msg)) ComponentOrHTML {
+	if c.Single {
+		return Tag("single")
+	}
+	var l List
+	for _, r := range c.Rows {
+		l = append(l, Tag("row", Markup(Attribute("id", r))))
+	}
+	return l
+}
+
+func (c *rowsComponent) SkipRender(prev Component) bool { return false }
+
+func (c *rowsComponent) Unmount() { *c.unmounted++ }
+
+// TestFragment tests rendering a component that renders a fragment as it
+// grows, shrinks, turns into a single element and back, and is removed.
+func TestFragment(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.strings.mock(`global.Get("document").Get("readyState")`, "complete")
+	ts.strings.mock(`global.Get("document").Call("querySelector", "body").Get("nodeName")`, "BODY")
+	ts.truthies.mock(`global.Get("document").Call("querySelector", "body")`, true)
+
+	var (
+		rows      = []string{"a", "b"}
+		single    bool
+		show      = true
+		unmounted int
+	)
+	comp := &componentFunc{
+		render: func() ComponentOrHTML {
+			c := []MarkupOrChild{Tag("head")}
+			if show {
+				c = append(c, &rowsComponent{Rows: rows, Single: single, unmounted: &unmounted})
+			}
+			return Tag("body", append(c, Tag("foot"))...)
+		},
+		skipRender: func(prev Component) bool { return false },
+	}
+
+	batch := newBatchRenderer(nil)
+	renderBody(batch, comp, send)
+
+	step := func(name string) {
+		ts.record("// " + name)
+		rerender(batch, comp, send)
+		ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+		ts.invokeCallbackRequestAnimationFrame(0)
+	}
+
+	rows = []string{"a", "b", "c"}
+	step("grow")
+
+	rows = []string{"a"}
+	step("shrink")
+
+	single = true
+	step("fragment to single element")
+
+	single = false
+	rows = []string{"a", "b"}
+	step("single element to fragment")
+
+	show = false
+	step("remove")
+	ts.record(fmt.Sprintf("// unmounted %d", unmounted))
+}
+
+// termComponent is a keyed component that renders a fragment of keyed
+// elements.
+type termComponent struct {
+	Core
+	term string
+}
+
+func (c *termComponent) Render(send func(Msg)) ComponentOrHTML {
+	return List{
+		Tag("dt", Markup(ElementKey(c.term))),
+		Tag("dd", Markup(ElementKey(c.term+"'"))),
+	}
+}
+
+func (c *termComponent) SkipRender(prev Component) bool { return false }
+
+func (c *termComponent) Key() interface{} { return c.term }
+
+// TestFragment_Keyed tests reordering the keyed children of a fragment, and
+// the keyed fragments of a parent.
+func TestFragment_Keyed(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.strings.mock(`global.Get("document").Get("readyState")`, "complete")
+	ts.strings.mock(`global.Get("document").Call("querySelector", "body").Get("nodeName")`, "BODY")
+	ts.truthies.mock(`global.Get("document").Call("querySelector", "body")`, true)
+
+	order := []string{"x", "y"}
+	comp := &componentFunc{
+		render: func() ComponentOrHTML {
+			var l List
+			for _, k := range order {
+				l = append(l, &termComponent{term: k})
+			}
+			return Tag("body", Tag("dl", l))
+		},
+		skipRender: func(prev Component) bool { return false },
+	}
+
+	batch := newBatchRenderer(nil)
+	renderBody(batch, comp, send)
+
+	order = []string{"y", "x"}
+	ts.record("// reorder")
+	rerender(batch, comp, send)
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.invokeCallbackRequestAnimationFrame(0)
+}
@@ -4,16 +4,13 @@
 package masc
 
 import (
+	"context"
 	"fmt"
 
 	ev "github.com/gost-dom/browser/dom/event"
 	"github.com/gost-dom/browser/html"
 )
 
-// RenderComponentInto renders the given Model into the <body> of the provided gost-dom Window.
-// It configures masc to use gost-dom, performs the initial render, and wires subsequent re-renders
-// automatically upon messages (e.g., from event listeners).
-// Returns a Body handle for inspection.
 // Body proxies interactions to the current document body.
 type Body struct {
 	win html.Window
@@ -43,60 +40,107 @@ func (b Body) Dispatch(selector, eventType string) error {
 	WrapGostNode(node).Call("dispatchEvent", ge)
 	return nil
 }
+
+// DispatchWindow dispatches a DOM event of the given type on the window, as
+// the browser does for events such as resize, online or popstate. Event
+// properties, such as the oldURL of a hashchange event, can be set in props.
+func (b Body) DispatchWindow(eventType string, props map[string]interface{}) {
+	b.win.DispatchEvent(&ev.Event{Type: eventType, Data: props})
+}
+
+// DispatchDocument dispatches a DOM event of the given type on the document,
+// as the browser does for events such as keydown or visibilitychange. Event
+// properties, such as the key of a keydown event, can be set in props.
+func (b Body) DispatchDocument(eventType string, props map[string]interface{}) {
+	b.win.Document().DispatchEvent(&ev.Event{Type: eventType, Data: props})
+}
+
+// RenderComponentInto renders the given Model into the <body> of the provided
+// gost-dom Window, as RenderComponentIntoWithSend does, and returns a Body
+// handle for inspection.
 func RenderComponentInto(win html.Window, m Model) (Body, error) {
+	body, _, err := RenderComponentIntoWithSend(win, m)
+	return body, err
+}
+
+// RenderComponentIntoWithSend renders the given Model into the <body> of the
+// provided gost-dom Window. It returns a Body handle and the send function
+// used for dispatching messages.
+//
+// Unlike a Program, the model is updated and re-rendered before send returns,
+// so that tests can inspect the DOM right away. The commands returned by Init
+// and Update are run the same way: synchronously, in order, until none are
+// left or the model quits. Time-based commands therefore block for their
+// duration, and models that keep issuing commands, such as a ticking clock,
+// are better tested with a Program.
+func RenderComponentIntoWithSend(win html.Window, m Model) (Body, func(Msg), error) {
 	// Configure masc to use gost-dom via Window
 	UseGostDOM(win)
-	// Obtain the document and <body> element
-	doc := win.Document()
-	body := doc.Body()
-	if body == nil {
-		return Body{}, fmt.Errorf("gostdom: <body> element not found")
-	}
-	// Wrap for masc
-	root := WrapGostNode(body)
-	// Local model copy
-	model := m
-	// Send function triggers re-render on the current <body>
-	var send func(Msg)
-	send = func(msg Msg) {
-		updated, _ := model.Update(msg)
-		model = updated
-		// Re-query the <body> element and wrap as root
-		body = win.Document().Body()
-		root = WrapGostNode(body)
-		_ = RenderIntoNode(root, model, send)
+	if win.Document().Body() == nil {
+		return Body{}, nil, fmt.Errorf("gostdom: <body> element not found")
 	}
+	// Commands find the clock and the commands started by Replace, Debounce
+	// and Throttle in their context, as they do in a Program.
+	ctx := ContextWithClock(context.Background(), useClock())
+	ctx = context.WithValue(ctx, keyedCmdsKey{}, newKeyedCmds())
+	r := &gostRunner{win: win, model: m, ctx: ctx}
 	// Initial render
-	if err := RenderIntoNode(root, model, send); err != nil {
-		return Body{}, err
+	if err := r.render(); err != nil {
+		return Body{}, nil, err
 	}
-	// After render, the <body> element may have been replaced, re-query it
-	// Return a body handle for tests
-	return Body{win: win}, nil
+	r.run(m.Init())
+	return Body{win: win}, r.send, nil
 }
 
-// RenderComponentIntoWithSend renders the given Model into the <body> of the provided gost-dom Window.
-// It returns a Body handle and the send function used for dispatching messages.
-func RenderComponentIntoWithSend(win html.Window, m Model) (Body, func(Msg), error) {
-	UseGostDOM(win)
-	doc := win.Document()
-	bodyNode := doc.Body()
-	if bodyNode == nil {
-		return Body{}, nil, fmt.Errorf("gostdom: <body> element not found")
+// gostRunner runs the Model of the gost-dom helpers.
+type gostRunner struct {
+	win   html.Window
+	model Model
+	ctx   context.Context
+	quit  bool
+}
+
+// render renders the model into the current <body> element, which the
+// previous render may have replaced.
+func (r *gostRunner) render() error {
+	return RenderIntoNode(WrapGostNode(r.win.Document().Body()), r.model, r.send)
+}
+
+// send updates the model with msg, re-renders it and runs the resulting
+// command.
+func (r *gostRunner) send(msg Msg) {
+	if r.quit {
+		return
 	}
-	root := WrapGostNode(bodyNode)
-	model := m
-	var send func(Msg)
-	send = func(msg Msg) {
-		updated, _ := model.Update(msg)
-		model = updated
-		// Re-query and wrap the current <body> element before re-render
-		bodyNode := win.Document().Body()
-		root = WrapGostNode(bodyNode)
-		_ = RenderIntoNode(root, model, send)
+	var cmd Cmd
+	r.model, cmd = r.model.Update(msg)
+	_ = r.render()
+	r.run(cmd)
+}
+
+// run runs cmd and delivers its result, expanding the messages of Batch,
+// Sequence, ContextCmd and Prioritize commands as a Program does.
+func (r *gostRunner) run(cmd Cmd) {
+	if cmd == nil || r.quit {
+		return
 	}
-	if err := RenderIntoNode(root, model, send); err != nil {
-		return Body{}, nil, err
+	switch msg := cmd().(type) {
+	case nil:
+	case QuitMsg:
+		r.quit = true
+	case BatchMsg:
+		for _, cmd := range msg {
+			r.run(cmd)
+		}
+	case sequenceMsg:
+		for _, cmd := range msg {
+			r.run(cmd)
+		}
+	case contextCmdMsg:
+		r.run(func() Msg { return msg(r.ctx) })
+	case PriorityMsg:
+		r.run(msg.Cmd)
+	default:
+		r.send(msg)
 	}
-	return Body{win: win}, send, nil
 }
@@ -0,0 +1,226 @@
+package masc
+
+import (
+	"errors"
+	"strings"
+)
+
+// Hydrate renders the given component into the existing HTML element found by
+// the CSS selector, adopting the server-rendered DOM nodes inside it rather
+// than replacing them. Event listeners are attached to the adopted nodes.
+//
+// Nodes that do not match the render of the component are replaced, and each
+// mismatch is logged as a warning. If there were any, an error of type
+// HydrationError is returned once the component is rendered. If the document
+// is still loading, hydration waits for it to load, and mismatches are only
+// logged.
+//
+// Otherwise, Hydrate behaves as RenderInto, and returns the same errors.
+func Hydrate(selector string, c Component, send func(Msg)) error {
+	target := global().Get("document").Call("querySelector", selector)
+	return hydrateIntoNode("Hydrate", target, c, send, newBatchRenderer(nil))
+}
+
+// HydrationError is returned when the server-rendered DOM does not match the
+// render of the component being hydrated.
+type HydrationError struct {
+	method string
+	// Mismatches describes each node that was replaced, added or removed.
+	Mismatches []string
+}
+
+func (e HydrationError) Error() string {
+	return "masc: " + e.method + ": server-rendered HTML does not match: " + strings.Join(e.Mismatches, "; ")
+}
+
+// HydrationErrorMsg is sent to the model when the server-rendered HTML
+// hydrated by a program configured with WithHydration does not match its
+// first render.
+type HydrationErrorMsg struct {
+	Err error
+}
+
+func hydrateIntoNode(methodName string, node jsObject, c Component, send func(Msg), batch *batchRenderer) error {
+	if !node.Truthy() {
+		return InvalidTargetError{method: methodName}
+	}
+	// block batch until we're done
+	batch.scheduled = true
+	doc := global().Get("document")
+	if doc.Get("readyState").String() == "loading" {
+		// The server-rendered DOM is complete once the document is loaded.
+		var cb jsFunc
+		cb = funcOf(func(this jsObject, args []jsObject) interface{} {
+			cb.Release()
+
+			var mismatch HydrationError
+			if err := hydrateIntoNode(methodName, node, c, send, batch); err != nil && !errors.As(err, &mismatch) {
+				panic(err)
+			}
+			return undefined()
+		})
+		doc.Call("addEventListener", "DOMContentLoaded", cb)
+		return nil
+	}
+
+	d := &hydration{next: node}
+	batch.hydration = d
+	nextRender, skip, pendingMounts := renderComponent(c, nil, send, batch)
+	batch.hydration = nil
+	if skip {
+		panic("masc: " + methodName + ": Component.SkipRender illegally returned true")
+	}
+	expectTag := toLower(node.Get("nodeName").String())
+	if nextRender == nil {
+		return ElementMismatchError{method: methodName, got: "fragment", want: expectTag}
+	}
+	if nextRender.tag != expectTag {
+		return ElementMismatchError{method: methodName, got: nextRender.tag, want: expectTag}
+	}
+	nextRender.hydrated = false
+	replaceNode(nextRender.node, node)
+	mount(pendingMounts...)
+	if m, ok := c.(Mounter); ok {
+		mount(m)
+	}
+	requestAnimationFrame(batch.program, batch.render, send)
+	if len(d.mismatches) > 0 {
+		return HydrationError{method: methodName, Mismatches: d.mismatches}
+	}
+	return nil
+}
+
+// hydration tracks the server-rendered DOM nodes adopted by a first render,
+// in document order.
+type hydration struct {
+	// next is the node to adopt next, if any is left in its parent.
+	next jsObject
+	// fresh is set while rendering the descendants of a node that was not
+	// adopted, which have no server-rendered nodes.
+	fresh      bool
+	mismatches []string
+}
+
+// hydrating reports whether the batch is rendering a hydration.
+func (b *batchRenderer) hydrating() bool {
+	return b != nil && b.hydration != nil
+}
+
+// adopt sets the node of h, a new element or text node, to the next
+// server-rendered node, replacing it by a new node if it does not match. The
+// children of an adopted element are adopted from its child nodes, until the
+// returned function is called once they are rendered.
+func (d *hydration) adopt(h *HTML) (done func()) {
+	if d.fresh {
+		h.createNode()
+		return func() {}
+	}
+	node := d.skip(h.tag != "")
+	if node == nil {
+		// The server rendered fewer nodes, so insert a new one.
+		d.mismatch("missing " + describeHTML(h))
+		h.createNode()
+		d.fresh = true
+		return func() { d.fresh = false }
+	}
+	after := node.Get("nextSibling")
+	d.next = after
+	// The node is already in place, so its parent must not insert it.
+	h.hydrated = true
+
+	name := toLower(node.Get("nodeName").String())
+	switch {
+	case h.tag == "" && name == "#text":
+		h.node = node
+		if text := node.Get("nodeValue").String(); text != h.text {
+			d.mismatch("expected " + describeHTML(h) + `, found text "` + text + `"`)
+			node.Set("nodeValue", h.text)
+		}
+		return func() {}
+	case h.tag != "" && name == toLower(h.tag):
+		h.node = node
+		if h.innerHTML != "" {
+			// The content of the element is replaced with its innerHTML.
+			return func() {}
+		}
+		d.next = node.Get("firstChild")
+		return func() {
+			d.removeRest()
+			d.next = after
+		}
+	}
+	d.mismatch("expected " + describeHTML(h) + ", found " + describeNode(node))
+	h.createNode()
+	replaceNode(h.node, node)
+	d.fresh = true
+	return func() { d.fresh = false }
+}
+
+// suspend renders new nodes, rather than adopting them, until the returned
+// function is called.
+func (d *hydration) suspend() (resume func()) {
+	next, fresh := d.next, d.fresh
+	d.fresh = true
+	return func() {
+		d.next, d.fresh = next, fresh
+	}
+}
+
+// skip advances past the comments before the next node, which are not
+// rendered, and past whitespace text if an element is expected, and returns
+// it.
+func (d *hydration) skip(element bool) jsObject {
+	for d.next != nil && d.next.Truthy() && d.ignored(d.next, element) {
+		d.next = d.next.Get("nextSibling")
+	}
+	if d.next == nil || !d.next.Truthy() {
+		return nil
+	}
+	return d.next
+}
+
+// ignored reports whether node is a comment, or whitespace text that is
+// ignored if element is set.
+func (d *hydration) ignored(node jsObject, element bool) bool {
+	switch node.Get("nodeName").String() {
+	case "#comment":
+		return true
+	case "#text":
+		return element && strings.TrimSpace(node.Get("nodeValue").String()) == ""
+	}
+	return false
+}
+
+// removeRest removes the server-rendered nodes left in the current parent,
+// which the render has no children for.
+func (d *hydration) removeRest() {
+	for node := d.skip(true); node != nil; node = d.skip(true) {
+		d.next = node.Get("nextSibling")
+		d.mismatch("unexpected " + describeNode(node))
+		node.Get("parentNode").Call("removeChild", node)
+	}
+}
+
+// mismatch records a difference between the server-rendered DOM and the
+// render, and logs it as a warning.
+func (d *hydration) mismatch(s string) {
+	d.mismatches = append(d.mismatches, s)
+	global().Get("console").Call("warn", "masc: hydration mismatch:", s)
+}
+
+// describeHTML describes h in mismatches.
+func describeHTML(h *HTML) string {
+	if h.tag == "" {
+		return `text "` + h.text + `"`
+	}
+	return "<" + h.tag + ">"
+}
+
+// describeNode describes a DOM node in mismatches.
+func describeNode(node jsObject) string {
+	name := toLower(node.Get("nodeName").String())
+	if name == "#text" {
+		return `text "` + node.Get("nodeValue").String() + `"`
+	}
+	return "<" + name + ">"
+}
@@ -0,0 +1,73 @@
+package masc
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestHydrate tests that Hydrate adopts the matching server-rendered nodes,
+// and replaces or removes the others.
+func TestHydrate(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	// The server rendered:
+	//
+	//	<div id="app"><h1>Hello</h1><p>old</p><span></span></div>
+	app := `global.Get("document").Call("querySelector", "#app")`
+	h1 := app + `.Get("firstChild")`
+	p := h1 + `.Get("nextSibling")`
+	span := p + `.Get("nextSibling")`
+	nodes := []struct {
+		node, name, value string
+	}{
+		{node: app, name: "DIV"},
+		{node: h1, name: "H1"},
+		{node: h1 + `.Get("firstChild")`, name: "#text", value: "Hello"},
+		{node: p, name: "P"},
+		{node: p + `.Get("firstChild")`, name: "#text", value: "old"},
+		{node: span, name: "SPAN"},
+	}
+	for _, n := range nodes {
+		ts.truthies.mock(n.node, true)
+		ts.truthies.mock(n.node, true)
+		ts.strings.mock(n.node+`.Get("nodeName")`, n.name)
+		ts.strings.mock(n.node+`.Get("nodeName")`, n.name)
+		if n.value != "" {
+			ts.strings.mock(n.node+`.Get("nodeValue")`, n.value)
+		}
+	}
+	for _, last := range []string{h1, p, span} {
+		ts.truthies.mock(last+`.Get("firstChild").Get("nextSibling")`, false)
+		ts.truthies.mock(last+`.Get("firstChild").Get("nextSibling")`, false)
+	}
+	ts.truthies.mock(span+`.Get("nextSibling")`, false)
+	ts.truthies.mock(span+`.Get("nextSibling")`, false)
+	ts.truthies.mock(app, true)
+	ts.strings.mock(app+`.Get("nodeName")`, "DIV")
+	ts.strings.mock(`global.Get("document").Get("readyState")`, "complete")
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+
+	click := &EventListener{Name: "click"}
+	comp := &componentFunc{
+		render: func() ComponentOrHTML {
+			return Tag("div",
+				Tag("h1", Text("Hello")),
+				Tag("p", Markup(click), Text("new")),
+			)
+		},
+		skipRender: func(prev Component) bool { return false },
+	}
+	err := Hydrate("#app", comp, send)
+
+	var mismatch HydrationError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("got error %v, want a HydrationError", err)
+	}
+	for _, m := range mismatch.Mismatches {
+		ts.record("// " + m)
+	}
+	if click.wrapper == nil {
+		t.Fatal("event listener of hydrated node not attached")
+	}
+}
@@ -0,0 +1,17 @@
+// Package cmds gives masctest access to the messages with which package masc
+// runs Sequence and ContextCmd commands, without exporting them.
+package cmds
+
+import "context"
+
+// These are set by package masc. Commands are masc.Cmd values and messages are
+// masc.Msg values, which this package cannot refer to.
+var (
+	// Sequence returns the commands of msg, if it is the message of a
+	// masc.Sequence command.
+	Sequence func(msg interface{}) (cmds []interface{}, ok bool)
+
+	// Context returns the function of msg, if it is the message of a
+	// masc.ContextCmd command.
+	Context func(msg interface{}) (cmd func(context.Context) interface{}, ok bool)
+)
@@ -0,0 +1,147 @@
+package masc
+
+import "sort"
+
+// keyedMoves collects the DOM nodes of keyed children as they are reconciled,
+// to move them into their next order afterwards with as few DOM operations as
+// possible.
+//
+// The children whose nodes are in place keep their previous index. The longest
+// increasing subsequence of those indices is the largest set of children that
+// are already in order, so only the other children are moved, or inserted if
+// their nodes are new.
+type keyedMoves struct {
+	// index maps the keys of the previous children to their position.
+	index map[interface{}]int
+	// end is the DOM node following the previous children, if any.
+	end      jsObject
+	children []keyedMove
+}
+
+// keyedMove is a keyed child, with its previous position, or -1 if its DOM
+// nodes must be inserted.
+type keyedMove struct {
+	nodes []*HTML
+	prev  int
+}
+
+// newKeyedMoves returns the moves of the keyed children of h, reconciled
+// against those of prev. It must be called before the DOM nodes of prev are
+// changed.
+func newKeyedMoves(h, prev *HTML) *keyedMoves {
+	m := &keyedMoves{index: make(map[interface{}]int, len(prev.children))}
+	for i, child := range prev.children {
+		if keyer, ok := child.(Keyer); ok && keyer.Key() != nil {
+			m.index[keyer.Key()] = i
+		}
+	}
+
+	// The children end before the node following the last previous node.
+	// Without previous nodes, they are inserted as any other new child.
+	for i := len(prev.children) - 1; i >= 0; i-- {
+		if nodes := childNodes(prev.children[i], nil); len(nodes) > 0 {
+			m.end = nodes[len(nodes)-1].nextSibling()
+			return m
+		}
+	}
+	switch {
+	case h.insertBeforeNode != nil:
+		m.end = h.insertBeforeNode
+	case h.lastRenderedChild != nil:
+		m.end = h.lastRenderedChild.nextSibling()
+	default:
+		m.end = h.firstChild()
+	}
+	return m
+}
+
+// add appends the child with the given key, which rendered nodes. If inPlace,
+// the nodes are where those of the previous child with the key were.
+func (m *keyedMoves) add(key interface{}, nodes []*HTML, inPlace bool) {
+	prev, ok := m.index[key]
+	if !ok || !inPlace {
+		prev = -1
+	}
+	m.children = append(m.children, keyedMove{nodes: nodes, prev: prev})
+}
+
+// apply moves the nodes of the children into place in parent. Each child that
+// is not kept in place is inserted before the first node of the child that
+// follows it, starting from the last.
+func (m *keyedMoves) apply(parent *HTML) {
+	prev := make([]int, len(m.children))
+	for i, c := range m.children {
+		prev[i] = c.prev
+	}
+	keep := increasingSubsequence(prev)
+
+	next := m.end
+	for i := len(m.children) - 1; i >= 0; i-- {
+		c := m.children[i]
+		if !keep[i] {
+			for _, node := range c.nodes {
+				parent.insertBefore(next, node)
+			}
+		}
+		if len(c.nodes) > 0 {
+			next = c.nodes[0].node
+		}
+	}
+
+	// Subsequent siblings follow the last child.
+	for i := len(m.children) - 1; i >= 0; i-- {
+		if nodes := m.children[i].nodes; len(nodes) > 0 {
+			parent.lastRenderedChild = nodes[len(nodes)-1]
+			break
+		}
+	}
+	if parent.insertBeforeNode != nil {
+		parent.insertBeforeNode = m.end
+	}
+}
+
+// sameNodes reports whether a and b hold the same DOM nodes, in the same order.
+func sameNodes(a, b []*HTML) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].node.Equal(b[i].node) {
+			return false
+		}
+	}
+	return true
+}
+
+// increasingSubsequence reports which elements of seq belong to a longest
+// strictly increasing subsequence of its non-negative elements.
+func increasingSubsequence(seq []int) []bool {
+	keep := make([]bool, len(seq))
+	// tails[k] is the index of the smallest last element of an increasing
+	// subsequence of length k+1, and prev links each element to the one
+	// before it in its subsequence.
+	var tails []int
+	prev := make([]int, len(seq))
+	for i, v := range seq {
+		if v < 0 {
+			continue
+		}
+		k := sort.Search(len(tails), func(k int) bool { return seq[tails[k]] >= v })
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	if len(tails) == 0 {
+		return keep
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+		keep[i] = true
+	}
+	return keep
+}
@@ -0,0 +1,131 @@
+package masctest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/octoberswimmer/masc"
+)
+
+// Epoch is the time at which clocks created by masctest start.
+var Epoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// FakeClock is a masc.Clock whose time only moves when Advance is called.
+// Use it with masc.WithClock to test programs that use Tick, Every or
+// Interval without waiting for real time to pass.
+//
+// Example:
+//
+//	clock := masctest.NewFakeClock(masctest.Epoch)
+//	p := masc.NewProgram(model, masc.WithClock(clock))
+//	go p.Run()
+//	clock.BlockUntil(1)
+//	clock.Advance(time.Second)
+type FakeClock struct {
+	mtx    sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mtx)
+	return c
+}
+
+// Now returns the clock's current time.
+func (c *FakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+// NewTimer returns a Timer that fires once the clock has been advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) masc.Timer {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	t := &fakeTimer{clock: c, when: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves the clock forward by d, firing every timer that becomes due in
+// the order of their deadlines.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	end := c.now.Add(d)
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].when.Before(c.timers[j].when)
+	})
+	for len(c.timers) > 0 && !c.timers[0].when.After(end) {
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		c.now = t.when
+		t.c <- t.when
+	}
+	c.now = end
+	c.cond.Broadcast()
+}
+
+// Timers returns the number of timers waiting to fire.
+func (c *FakeClock) Timers() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntil waits until at least n timers are waiting to fire. Commands run
+// in the background, so call it before Advance to make sure the timers a
+// command creates exist.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// stop removes t from the pending timers.
+func (c *FakeClock) stop(t *fakeTimer) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	c     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+func (t *fakeTimer) Stop() bool          { return t.clock.stop(t) }
+
+// virtualClock is the clock of a Program. Commands run synchronously, so
+// nothing else could advance time while one waits: creating a timer advances
+// the clock to its deadline straight away.
+type virtualClock struct {
+	*FakeClock
+}
+
+func (c virtualClock) NewTimer(d time.Duration) masc.Timer {
+	t := c.FakeClock.NewTimer(d)
+	c.Advance(d)
+	return t
+}
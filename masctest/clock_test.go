@@ -0,0 +1,179 @@
+package masctest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/octoberswimmer/masc"
+	"github.com/octoberswimmer/masc/internal/cmds"
+)
+
+func TestFakeClock(t *testing.T) {
+	c := NewFakeClock(Epoch)
+	late := c.NewTimer(2 * time.Second)
+	early := c.NewTimer(time.Second)
+	stopped := c.NewTimer(time.Second)
+	if !stopped.Stop() {
+		t.Fatal("expected Stop to stop a pending timer")
+	}
+	if c.Timers() != 2 {
+		t.Fatalf("expected 2 pending timers, got %d", c.Timers())
+	}
+
+	c.Advance(1500 * time.Millisecond)
+	select {
+	case now := <-early.C():
+		if want := Epoch.Add(time.Second); !now.Equal(want) {
+			t.Fatalf("timer fired at %v, want %v", now, want)
+		}
+	default:
+		t.Fatal("expected early timer to fire")
+	}
+	select {
+	case <-late.C():
+		t.Fatal("expected late timer not to fire yet")
+	default:
+	}
+	if want := Epoch.Add(1500 * time.Millisecond); !c.Now().Equal(want) {
+		t.Fatalf("clock is at %v, want %v", c.Now(), want)
+	}
+
+	c.Advance(time.Second)
+	<-late.C()
+	if late.Stop() {
+		t.Fatal("expected Stop to report a fired timer")
+	}
+}
+
+func TestFakeClockTick(t *testing.T) {
+	clock := NewFakeClock(Epoch)
+	masc.UseClock(clock)
+	defer masc.UseClock(nil)
+
+	cmd := masc.Tick(time.Second, func(t time.Time) masc.Msg { return tickMsg(t) })
+	msgs := make(chan masc.Msg)
+	go func() { msgs <- cmd() }()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if msg := <-msgs; !time.Time(msg.(tickMsg)).Equal(Epoch.Add(time.Second)) {
+		t.Fatalf("unexpected tick %v", msg)
+	}
+}
+
+func TestFakeClockTickContext(t *testing.T) {
+	clock := NewFakeClock(Epoch)
+	ctx := masc.ContextWithClock(context.Background(), clock)
+	cmd := masc.TickContext(time.Second, func(t time.Time) masc.Msg { return tickMsg(t) })
+	msgs := make(chan masc.Msg)
+	go func() {
+		run, _ := cmds.Context(cmd())
+		msgs <- run(ctx)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if msg := <-msgs; !time.Time(msg.(tickMsg)).Equal(Epoch.Add(time.Second)) {
+		t.Fatalf("unexpected tick %v", msg)
+	}
+}
+
+func TestFakeClockYield(t *testing.T) {
+	clock := NewFakeClock(Epoch)
+	masc.UseClock(clock)
+	defer masc.UseClock(nil)
+
+	done := make(chan struct{})
+	go func() {
+		masc.Yield()
+		close(done)
+	}()
+
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("expected Yield to wait for the clock")
+	default:
+	}
+	clock.Advance(16 * time.Millisecond)
+	<-done
+}
+
+type tickMsg time.Time
+
+type tickModel struct {
+	masc.Core
+	ticks int
+}
+
+func (m *tickModel) tick() masc.Cmd {
+	return masc.TickContext(time.Second, func(t time.Time) masc.Msg { return tickMsg(t) })
+}
+
+func (m *tickModel) Init() masc.Cmd { return m.tick() }
+
+func (m *tickModel) Update(msg masc.Msg) (masc.Model, masc.Cmd) {
+	if _, ok := msg.(tickMsg); ok {
+		m.ticks++
+		if m.ticks == 3 {
+			return m, masc.Quit
+		}
+		return m, m.tick()
+	}
+	return m, nil
+}
+
+func (m *tickModel) Render(send func(masc.Msg)) masc.ComponentOrHTML { return nil }
+
+func TestVirtualTime(t *testing.T) {
+	p := New(t, &tickModel{})
+	p.RunCmds()
+	if m := p.Model().(*tickModel); m.ticks != 3 {
+		t.Fatalf("expected 3 ticks, got %d", m.ticks)
+	}
+	if want := Epoch.Add(3 * time.Second); !p.Now().Equal(want) {
+		t.Fatalf("virtual time is %v, want %v", p.Now(), want)
+	}
+}
+
+type (
+	fetchedMsg  string
+	timedOutMsg struct{}
+)
+
+type timeoutModel struct {
+	masc.Core
+	cmd masc.Cmd
+	msg masc.Msg
+}
+
+func (m *timeoutModel) Init() masc.Cmd {
+	return masc.Timeout(time.Second, m.cmd, timedOutMsg{})
+}
+
+func (m *timeoutModel) Update(msg masc.Msg) (masc.Model, masc.Cmd) {
+	m.msg = msg
+	return m, nil
+}
+
+func (m *timeoutModel) Render(send func(masc.Msg)) masc.ComponentOrHTML { return nil }
+
+func TestVirtualTimeout(t *testing.T) {
+	fast := func() masc.Msg { return fetchedMsg("fast") }
+	p := New(t, &timeoutModel{cmd: fast})
+	p.RunCmds()
+	if m := p.Model().(*timeoutModel); m.msg != fetchedMsg("fast") {
+		t.Fatalf("expected the fast command to beat its timeout, got %v", m.msg)
+	}
+	if !p.Now().Equal(Epoch) {
+		t.Fatalf("virtual time is %v, want %v", p.Now(), Epoch)
+	}
+
+	slow := masc.TickContext(2*time.Second, func(time.Time) masc.Msg { return fetchedMsg("slow") })
+	p = New(t, &timeoutModel{cmd: slow})
+	p.RunCmds()
+	if m := p.Model().(*timeoutModel); m.msg != (timedOutMsg{}) {
+		t.Fatalf("expected the slow command to time out, got %v", m.msg)
+	}
+}
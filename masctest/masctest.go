@@ -0,0 +1,214 @@
+// Package masctest provides a deterministic, headless runner for testing
+// masc Models.
+//
+// A Program runs a Model through Init and Update without a DOM. Commands
+// returned by the model are queued instead of being run in the background, and
+// the test decides when they run with RunNext or RunCmds. Batch, Sequence,
+// ContextCmd and Prioritize commands are expanded the same way a masc.Program
+// expands them, although priorities do not change the order in which queued
+// commands run.
+//
+// Time is virtual: commands such as masc.TickContext and masc.EveryContext
+// complete as soon as they run, moving the program's clock forward instead of
+// sleeping. Now reports the virtual time. A masc.Timeout command times out
+// when its command waits on the clock past the deadline.
+//
+// Example:
+//
+//	func TestLoad(t *testing.T) {
+//	    p := masctest.New(t, &model{})
+//	    p.Send(loadMsg{})
+//	    p.RunCmds()
+//
+//	    m := p.Model().(*model)
+//	    if !m.loaded {
+//	        t.Fatal("expected model to be loaded")
+//	    }
+//	}
+package masctest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/octoberswimmer/masc"
+	"github.com/octoberswimmer/masc/internal/cmds"
+)
+
+// maxCmds bounds the number of commands RunCmds executes, so that models which
+// keep scheduling commands fail the test instead of hanging.
+const maxCmds = 10000
+
+// Program runs a masc.Model under test control.
+type Program struct {
+	t     testing.TB
+	ctx   context.Context
+	clock *FakeClock
+
+	model   masc.Model
+	pending []masc.Cmd
+	msgs    []masc.Msg
+	quit    bool
+}
+
+// New creates a Program for model and calls its Init method. The command
+// returned by Init is queued but not run.
+//
+// Contexts passed to ContextCmd commands are cancelled when the test ends. The
+// program's clock starts at Epoch.
+func New(t testing.TB, model masc.Model) *Program {
+	t.Helper()
+	clock := NewFakeClock(Epoch)
+	ctx, cancel := context.WithCancel(masc.ContextWithClock(context.Background(), virtualClock{clock}))
+	t.Cleanup(cancel)
+	p := &Program{
+		t:     t,
+		ctx:   ctx,
+		clock: clock,
+		model: model,
+	}
+	p.queue(model.Init())
+	return p
+}
+
+// Model returns the current model.
+func (p *Program) Model() masc.Model {
+	return p.model
+}
+
+// Messages returns the messages delivered to the model's Update method so far,
+// in order. Internal messages such as masc.BatchMsg are not included.
+func (p *Program) Messages() []masc.Msg {
+	return append([]masc.Msg(nil), p.msgs...)
+}
+
+// Quitting reports whether the model has asked the program to quit. Once it
+// has, no further messages are delivered.
+func (p *Program) Quitting() bool {
+	return p.quit
+}
+
+// Now returns the program's virtual time.
+func (p *Program) Now() time.Time {
+	return p.clock.Now()
+}
+
+// Pending returns the number of queued commands.
+func (p *Program) Pending() int {
+	return len(p.pending)
+}
+
+// Send delivers msg to the model, as masc.Program.Send would. The command
+// returned by Update is queued but not run.
+func (p *Program) Send(msg masc.Msg) {
+	p.t.Helper()
+	switch msg := msg.(type) {
+	case nil:
+		return
+	case masc.QuitMsg:
+		p.quit = true
+		return
+	case masc.BatchMsg:
+		for _, cmd := range msg {
+			p.queue(cmd)
+		}
+		return
+	case masc.PriorityMsg:
+		p.queue(msg.Cmd)
+		return
+	}
+	if isCmdMsg(msg) {
+		p.queue(func() masc.Msg { return msg })
+		return
+	}
+	if p.quit {
+		return
+	}
+	p.msgs = append(p.msgs, msg)
+	var cmd masc.Cmd
+	p.model, cmd = p.model.Update(msg)
+	p.queue(cmd)
+}
+
+// RunNext runs the oldest queued command and delivers the resulting messages.
+// It reports whether there was a command to run.
+func (p *Program) RunNext() bool {
+	p.t.Helper()
+	if len(p.pending) == 0 {
+		return false
+	}
+	cmd := p.pending[0]
+	p.pending = p.pending[1:]
+	p.run(cmd)
+	return true
+}
+
+// RunCmds runs queued commands, including the ones they lead to, until none
+// are left or the model quits.
+func (p *Program) RunCmds() {
+	p.t.Helper()
+	for n := 0; !p.quit && p.RunNext(); n++ {
+		if n >= maxCmds {
+			p.t.Fatalf("masctest: gave up after running %d commands", maxCmds)
+		}
+	}
+}
+
+// queue adds cmd to the pending commands.
+func (p *Program) queue(cmd masc.Cmd) {
+	if cmd != nil {
+		p.pending = append(p.pending, cmd)
+	}
+}
+
+// run executes cmd and delivers its result.
+func (p *Program) run(cmd masc.Cmd) {
+	p.t.Helper()
+	p.handle(cmd())
+}
+
+// handle delivers msg, the result of a command. Sequences are run to
+// completion immediately, including every command of a batch they contain.
+func (p *Program) handle(msg masc.Msg) {
+	p.t.Helper()
+	if seq, ok := cmds.Sequence(msg); ok {
+		for _, cmd := range seq {
+			cmd := cmd.(masc.Cmd)
+			if cmd == nil || p.quit {
+				continue
+			}
+			result := cmd()
+			if prioritized, ok := result.(masc.PriorityMsg); ok && prioritized.Cmd != nil {
+				result = prioritized.Cmd()
+			}
+			if batch, ok := result.(masc.BatchMsg); ok {
+				for _, cmd := range batch {
+					if cmd != nil {
+						p.run(cmd)
+					}
+				}
+				continue
+			}
+			p.handle(result)
+		}
+		return
+	}
+	if run, ok := cmds.Context(msg); ok {
+		ctx, cancel := context.WithCancel(p.ctx)
+		defer cancel()
+		p.handle(run(ctx))
+		return
+	}
+	p.Send(msg)
+}
+
+// isCmdMsg reports whether msg is the message of a Sequence or ContextCmd
+// command, which is run rather than delivered.
+func isCmdMsg(msg masc.Msg) bool {
+	if _, ok := cmds.Sequence(msg); ok {
+		return true
+	}
+	_, ok := cmds.Context(msg)
+	return ok
+}
@@ -0,0 +1,105 @@
+package masctest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/octoberswimmer/masc"
+)
+
+type (
+	addMsg    int
+	loadMsg   struct{}
+	loadedMsg string
+)
+
+type model struct {
+	masc.Core
+	total  int
+	loaded []string
+}
+
+func (m *model) Init() masc.Cmd {
+	return func() masc.Msg { return addMsg(1) }
+}
+
+func (m *model) Update(msg masc.Msg) (masc.Model, masc.Cmd) {
+	switch msg := msg.(type) {
+	case addMsg:
+		m.total += int(msg)
+	case loadMsg:
+		load := func(name string) masc.Cmd {
+			return masc.ContextCmd(func(ctx context.Context) masc.Msg {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return loadedMsg(name)
+			})
+		}
+		return m, masc.Sequence(
+			load("a"),
+			masc.Batch(load("b"), load("c")),
+			load("d"),
+			masc.Quit,
+		)
+	case loadedMsg:
+		m.loaded = append(m.loaded, string(msg))
+	}
+	return m, nil
+}
+
+func (m *model) Render(send func(masc.Msg)) masc.ComponentOrHTML { return nil }
+
+func TestInit(t *testing.T) {
+	p := New(t, &model{})
+	if p.Pending() != 1 {
+		t.Fatalf("expected the Init command to be pending, got %d", p.Pending())
+	}
+	if m := p.Model().(*model); m.total != 0 {
+		t.Fatalf("expected Init command not to run yet, total is %d", m.total)
+	}
+	if !p.RunNext() {
+		t.Fatal("expected a command to run")
+	}
+	if m := p.Model().(*model); m.total != 1 {
+		t.Fatalf("expected total to be 1, got %d", m.total)
+	}
+	if p.RunNext() {
+		t.Fatal("expected no more commands")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	p := New(t, &model{})
+	p.RunCmds()
+	p.Send(masc.BatchMsg{
+		func() masc.Msg { return addMsg(2) },
+		func() masc.Msg { return addMsg(3) },
+	})
+	if p.Pending() != 2 {
+		t.Fatalf("expected 2 pending commands, got %d", p.Pending())
+	}
+	p.RunCmds()
+	if m := p.Model().(*model); m.total != 6 {
+		t.Fatalf("expected total to be 6, got %d", m.total)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	p := New(t, &model{})
+	p.RunCmds()
+	p.Send(loadMsg{})
+	p.RunCmds()
+
+	if got := fmt.Sprint(p.Model().(*model).loaded); got != "[a b c d]" {
+		t.Fatalf("expected commands to run in order, got %s", got)
+	}
+	if !p.Quitting() {
+		t.Fatal("expected the model to quit")
+	}
+	want := "[1 {} a b c d]"
+	if got := fmt.Sprint(p.Messages()); got != want {
+		t.Fatalf("got messages %s want %s", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+package masc
+
+import (
+	"runtime/debug"
+)
+
+// UpdateFunc has the signature of Model.Update, with the model passed
+// explicitly. It is the unit that Middleware wraps.
+type UpdateFunc func(Model, Msg) (Model, Cmd)
+
+// Middleware wraps the program's update function. A middleware receives the
+// next UpdateFunc in the chain and returns a new one, so it can inspect the
+// message and the model before calling next, inspect the resulting model and
+// Cmd afterwards, replace any of them, or not call next at all to drop the
+// message.
+//
+// QuitMsg passes through the middleware chain as well, so a middleware can
+// veto quitting by not calling next. So do the messages of Batch and
+// Sequence commands, whose commands are only run once they reach the end of
+// the chain.
+//
+// Example:
+//
+//	func logging(next masc.UpdateFunc) masc.UpdateFunc {
+//	    return func(m masc.Model, msg masc.Msg) (masc.Model, masc.Cmd) {
+//	        log.Printf("msg: %#v", msg)
+//	        m, cmd := next(m, msg)
+//	        log.Printf("model: %#v", m)
+//	        return m, cmd
+//	    }
+//	}
+//
+//	p := masc.NewProgram(model, masc.WithMiddleware(logging))
+type Middleware func(next UpdateFunc) UpdateFunc
+
+// filterMiddleware adapts a WithFilter event filter to a Middleware.
+func filterMiddleware(filter func(Model, Msg) Msg) Middleware {
+	return func(next UpdateFunc) UpdateFunc {
+		return func(model Model, msg Msg) (Model, Cmd) {
+			if msg = filter(model, msg); msg == nil {
+				return model, nil
+			}
+			return next(model, msg)
+		}
+	}
+}
+
+// UpdatePanicMsg is delivered to the model when an update panicked and the
+// program was started with WithUpdateRecovery.
+type UpdatePanicMsg struct {
+	// Msg is the message whose update panicked.
+	Msg Msg
+	// Value is the value the update panicked with.
+	Value interface{}
+	// Stack is the stack trace of the panic.
+	Stack []byte
+}
+
+// recoverMiddleware recovers panics in the updates of next. The update of a
+// panicking message is discarded and next is called with an UpdatePanicMsg
+// and the last good model instead. Models are copied before each update, like
+// the debugger copies them, so that changes made before the panic are rolled
+// back. A panic while handling the UpdatePanicMsg is not recovered.
+func recoverMiddleware(next UpdateFunc) UpdateFunc {
+	return func(model Model, msg Msg) (Model, Cmd) {
+		good := snapshot(model)
+		updated, cmd, panicMsg := tryUpdate(next, model, msg)
+		if panicMsg == nil {
+			return updated, cmd
+		}
+		// Take over the render state, which copies do not have, so that the
+		// DOM is reconciled rather than rebuilt.
+		*good.Context() = *model.Context()
+		return next(good, *panicMsg)
+	}
+}
+
+// tryUpdate calls update, returning an UpdatePanicMsg if it panicked.
+func tryUpdate(update UpdateFunc, model Model, msg Msg) (next Model, cmd Cmd, panicMsg *UpdatePanicMsg) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicMsg = &UpdatePanicMsg{Msg: msg, Value: r, Stack: debug.Stack()}
+		}
+	}()
+	next, cmd = update(model, msg)
+	return next, cmd, nil
+}
+
+// chain wraps update with the program's filter and middleware. The filter is
+// outermost, followed by middleware in the order it was registered. With
+// WithUpdateRecovery, panic recovery wraps all of them.
+func (p *Program) chain(update UpdateFunc) UpdateFunc {
+	for i := len(p.middleware) - 1; i >= 0; i-- {
+		update = p.middleware[i](update)
+	}
+	if p.filter != nil {
+		update = filterMiddleware(p.filter)(update)
+	}
+	if p.startupOptions.has(withUpdateRecovery) {
+		update = recoverMiddleware(update)
+	}
+	return update
+}
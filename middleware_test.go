@@ -0,0 +1,191 @@
+package masc
+
+import (
+	"fmt"
+	"testing"
+)
+
+type counterModel struct {
+	Core
+	n int
+}
+
+func (m *counterModel) Init() Cmd { return nil }
+
+func (m *counterModel) Update(msg Msg) (Model, Cmd) {
+	if _, ok := msg.(incrementMsg); ok {
+		return &counterModel{n: m.n + 1}, nil
+	}
+	return m, nil
+}
+
+func (m *counterModel) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+func TestMiddlewareChain(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next UpdateFunc) UpdateFunc {
+			return func(m Model, msg Msg) (Model, Cmd) {
+				before := m.(*counterModel).n
+				m, cmd := next(m, msg)
+				after := m.(*counterModel).n
+				calls = append(calls, fmt.Sprintf("%s:%d->%d", name, before, after))
+				return m, cmd
+			}
+		}
+	}
+	filter := func(_ Model, msg Msg) Msg {
+		calls = append(calls, "filter")
+		return msg
+	}
+
+	p := NewProgram(nil,
+		WithFilter(filter),
+		WithMiddleware(trace("a")),
+		WithMiddleware(trace("b"), nil),
+	)
+	update := p.chain(func(m Model, msg Msg) (Model, Cmd) { return m.Update(msg) })
+	m, _ := update(&counterModel{}, incrementMsg{})
+
+	if got := m.(*counterModel).n; got != 1 {
+		t.Fatalf("expected counter to be 1, got %d", got)
+	}
+	want := "[filter b:0->1 a:0->1]"
+	if got := fmt.Sprint(calls); got != want {
+		t.Fatalf("got calls %s want %s", got, want)
+	}
+}
+
+func TestMiddlewareFilterDrop(t *testing.T) {
+	p := NewProgram(nil, WithFilter(func(Model, Msg) Msg { return nil }))
+	called := false
+	update := p.chain(func(m Model, msg Msg) (Model, Cmd) {
+		called = true
+		return m, nil
+	})
+	update(&counterModel{}, incrementMsg{})
+	if called {
+		t.Fatal("expected update not to be called for a filtered message")
+	}
+}
+
+func TestMiddlewareVetoQuit(t *testing.T) {
+	vetoes := 0
+	veto := func(next UpdateFunc) UpdateFunc {
+		return func(m Model, msg Msg) (Model, Cmd) {
+			if _, ok := msg.(QuitMsg); ok && m.(*counterModel).n < 2 {
+				vetoes++
+				return m, nil
+			}
+			return next(m, msg)
+		}
+	}
+
+	p := NewProgram(&counterModel{}, WithoutRenderer(), WithMiddleware(veto))
+	go func() {
+		p.Quit()
+		p.Send(incrementMsg{})
+		p.Quit()
+		p.Send(incrementMsg{})
+		p.Quit()
+	}()
+
+	m, err := p.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.(*counterModel).n; got != 2 {
+		t.Fatalf("expected counter to be 2, got %d", got)
+	}
+	if vetoes != 2 {
+		t.Fatalf("expected 2 vetoed quits, got %d", vetoes)
+	}
+}
+
+type badMsg struct{}
+
+type recoveringModel struct {
+	Core
+	n      int
+	panics []UpdatePanicMsg
+}
+
+func (m *recoveringModel) Init() Cmd { return nil }
+
+func (m *recoveringModel) Update(msg Msg) (Model, Cmd) {
+	switch msg := msg.(type) {
+	case incrementMsg:
+		m.n++
+	case badMsg:
+		m.n++
+		panic("malformed")
+	case UpdatePanicMsg:
+		m.panics = append(m.panics, msg)
+	}
+	return m, nil
+}
+
+func (m *recoveringModel) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+// copyingRecoveringModel is a recoveringModel that implements Copier.
+type copyingRecoveringModel struct {
+	recoveringModel
+	copies int
+}
+
+func (m *copyingRecoveringModel) Update(msg Msg) (Model, Cmd) {
+	m.recoveringModel.Update(msg)
+	return m, nil
+}
+
+func (m *copyingRecoveringModel) Copy() Component {
+	cpy := *m
+	cpy.copies++
+	return &cpy
+}
+
+func TestUpdateRecovery(t *testing.T) {
+	t.Run("Copier", func(t *testing.T) {
+		m := runRecovering(t, &copyingRecoveringModel{})
+		if m.(*copyingRecoveringModel).copies == 0 {
+			t.Fatal("expected the model to be copied with Copy")
+		}
+	})
+	t.Run("Shallow", func(t *testing.T) {
+		runRecovering(t, &recoveringModel{})
+	})
+}
+
+// runRecovering runs model, whose second update panics, and checks that the
+// update was rolled back.
+func runRecovering(t *testing.T, model Model) Model {
+	p := NewProgram(model, WithoutRenderer(), WithUpdateRecovery())
+	go func() {
+		p.Send(incrementMsg{})
+		p.Send(badMsg{})
+		p.Send(incrementMsg{})
+		p.Quit()
+	}()
+
+	m, err := p.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got *recoveringModel
+	switch m := m.(type) {
+	case *recoveringModel:
+		got = m
+	case *copyingRecoveringModel:
+		got = &m.recoveringModel
+	}
+	if got.n != 2 {
+		t.Fatalf("expected the panicking update to be rolled back, got counter %d", got.n)
+	}
+	if len(got.panics) != 1 {
+		t.Fatalf("expected one UpdatePanicMsg, got %d", len(got.panics))
+	}
+	if msg := got.panics[0]; msg.Msg != (badMsg{}) || msg.Value != "malformed" || len(msg.Stack) == 0 {
+		t.Fatalf("expected the UpdatePanicMsg to describe the panic, got %+v", msg)
+	}
+	return m
+}
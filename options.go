@@ -3,6 +3,7 @@ package masc
 import (
 	"context"
 	"sync/atomic"
+	"time"
 )
 
 // ProgramOption is used to set options when initializing a Program. Program can
@@ -22,6 +23,92 @@ func WithContext(ctx context.Context) ProgramOption {
 	}
 }
 
+// WithClock sets the Clock used by the program's time-based commands and
+// subscriptions, such as TickContext, EveryContext and Interval. It is mostly
+// useful in tests, with a fake clock that is advanced manually.
+func WithClock(clock Clock) ProgramOption {
+	return func(p *Program) {
+		p.clock = clock
+	}
+}
+
+// WithCommandsTimeout makes [Program.Run] wait up to the given duration for
+// in-flight commands to return after the program quits or is killed. Commands
+// created with ContextCmd have their contexts cancelled at that point, so they
+// will usually return promptly.
+//
+// This is mostly useful in tests, to ensure no command outlives the Program.
+func WithCommandsTimeout(timeout time.Duration) ProgramOption {
+	return func(p *Program) {
+		p.commandsTimeout = timeout
+	}
+}
+
+// WithCommandConcurrency limits the number of commands that run at the same
+// time to n. Further commands wait in a queue ordered by Priority. Zero or less
+// means no limit, which is the default.
+//
+// Commands waiting on the Clock, such as TickContext, EveryContext, Debounce,
+// Throttle and Retry between attempts, do not count towards the limit while
+// they wait.
+//
+// Subscriptions are not commands and are not limited.
+func WithCommandConcurrency(n int) ProgramOption {
+	return func(p *Program) {
+		p.concurrency = n
+	}
+}
+
+// WithPersistence saves the model to store after it is updated, at most a few
+// times per second and once more when the program exits, and restores it from
+// store before Init is called. Models are encoded with codec, or with
+// JSONCodec if codec is nil. A model that encodes to the data saved last is
+// not saved again, and saves are written in order.
+//
+// Errors restoring or saving the model are delivered to Update as a
+// PersistErrorMsg. A model that cannot be restored starts from the model
+// passed to NewProgram.
+//
+// Example:
+//
+//	p := masc.NewProgram(&model{}, masc.WithPersistence(masc.LocalStorage("todos"), nil))
+func WithPersistence(store Store, codec Codec) ProgramOption {
+	if codec == nil {
+		codec = JSONCodec
+	}
+	return func(p *Program) {
+		p.persistence = &persistence{store: store, codec: codec}
+	}
+}
+
+// WithJSBridge lets JavaScript on the page talk to the program through
+// window[name], for example when the program is a widget embedded in a larger
+// JavaScript app.
+//
+// window[name].send(json) sends a message to the program. json is a string of
+// the form {"type": "...", "data": ...}, where type is a name registered with
+// RegisterMsg and data is decoded into a new message of the registered type.
+// send returns null, or an error message if the message was not understood.
+//
+// window[name].subscribe(callback) calls callback with each event emitted by
+// the program with EmitJS, as an object of the same form. It returns a
+// function that removes the subscription.
+//
+// Example:
+//
+//	masc.RegisterMsg("select", SelectMsg{})
+//	p := masc.NewProgram(model, masc.WithJSBridge("picker"))
+//
+// and in JavaScript:
+//
+//	picker.subscribe((event) => console.log(event.type, event.data));
+//	picker.send(JSON.stringify({type: "select", data: {id: "42"}}));
+func WithJSBridge(name string) ProgramOption {
+	return func(p *Program) {
+		p.bridge = &jsBridge{name: name}
+	}
+}
+
 // WithoutSignalHandler disables the signal handler that Bubble Tea sets up for
 // Programs. This is useful if you want to handle signals yourself.
 func WithoutSignalHandler() ProgramOption {
@@ -40,6 +127,64 @@ func WithoutCatchPanics() ProgramOption {
 	}
 }
 
+// WithTracer reports the timing of the program's messages, updates, renders
+// and commands to tracer, for example PerformanceTracer to see them in the
+// browser's devtools. They are timed with the system clock, whatever the
+// program's Clock.
+func WithTracer(tracer Tracer) ProgramOption {
+	return func(p *Program) {
+		p.tracer = tracer
+	}
+}
+
+// WithUpdateRecovery keeps the program running when Update panics. The panic
+// is recovered, the update is discarded, and Update is called with an
+// UpdatePanicMsg and the last good model instead, so the app can show the
+// error and continue. The model is copied before every update to roll back
+// changes made before the panic: with Copy if it implements Copier, otherwise
+// with a shallow copy, which does not roll back changes to the maps, slices
+// and pointers the model refers to.
+//
+// A panic while handling the UpdatePanicMsg ends the program as usual.
+//
+//	func (m *Dashboard) Update(msg masc.Msg) (masc.Model, masc.Cmd) {
+//		switch msg := msg.(type) {
+//		case masc.UpdatePanicMsg:
+//			m.err = fmt.Sprintf("could not handle %T: %v", msg.Msg, msg.Value)
+//		...
+//	}
+//
+//	p := masc.NewProgram(&Dashboard{}, masc.WithUpdateRecovery())
+func WithUpdateRecovery() ProgramOption {
+	return func(p *Program) {
+		p.startupOptions |= withUpdateRecovery
+	}
+}
+
+// WithHydration makes the program hydrate the server-rendered HTML of its
+// root node, or of the body, on its first render, as Hydrate does, instead of
+// replacing it.
+//
+// Mismatches between the server-rendered HTML and the first render are logged
+// as warnings, and reported to the model with a HydrationErrorMsg.
+//
+//	p := masc.NewProgram(&App{}, masc.WithHydration())
+func WithHydration() ProgramOption {
+	return func(p *Program) {
+		p.startupOptions |= withHydration
+	}
+}
+
+// WithoutFrameCoalescing renders the model after every message. By default,
+// messages received within one animation frame are all applied to the model
+// first, and the model is rendered once on the next frame. This is mostly
+// useful in tests that need a render per message.
+func WithoutFrameCoalescing() ProgramOption {
+	return func(p *Program) {
+		p.startupOptions |= withoutFrameCoalescing
+	}
+}
+
 // WithoutSignals will ignore OS signals.
 // This is mainly useful for testing.
 func WithoutSignals() ProgramOption {
@@ -91,12 +236,39 @@ func WithoutRenderer() ProgramOption {
 //		fmt.Println("Error running program:", err)
 //		os.Exit(1)
 //	}
+//
+// The filter sees every message sent to the program, including the messages
+// of Batch, Sequence and Prioritize commands, whether they are sent or
+// returned by Init, Update or another command, but not those of ContextCmd
+// commands, which are run by the program.
+//
+// A program has a single filter, which runs before any middleware set with
+// WithMiddleware; calling WithFilter again replaces it. Use WithMiddleware to
+// stack several independent concerns.
 func WithFilter(filter func(Model, Msg) Msg) ProgramOption {
 	return func(p *Program) {
 		p.filter = filter
 	}
 }
 
+// WithMiddleware adds middleware that wraps the program's update function.
+// Middleware runs in the order given, the first being outermost, and may be
+// added with several WithMiddleware options; later calls append rather than
+// replace.
+//
+// Example:
+//
+//	p := masc.NewProgram(model, masc.WithMiddleware(logging, recordUndo))
+func WithMiddleware(middleware ...Middleware) ProgramOption {
+	return func(p *Program) {
+		for _, m := range middleware {
+			if m != nil {
+				p.middleware = append(p.middleware, m)
+			}
+		}
+	}
+}
+
 // WithPanicHandler sets a custom panic handler that will be called when a
 // panic is caught by MASC. The panic handler function receives the panic value
 // and should handle displaying the panic error to the user in a way appropriate
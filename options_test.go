@@ -3,6 +3,7 @@ package masc
 import (
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestOptions(t *testing.T) {
@@ -30,6 +31,28 @@ func TestOptions(t *testing.T) {
 		}
 	})
 
+	t.Run("middleware", func(t *testing.T) {
+		identity := func(next UpdateFunc) UpdateFunc { return next }
+		p := NewProgram(nil, WithMiddleware(identity), WithMiddleware(identity))
+		if len(p.middleware) != 2 {
+			t.Errorf("expected 2 middleware, got %d", len(p.middleware))
+		}
+	})
+
+	t.Run("commands timeout", func(t *testing.T) {
+		p := NewProgram(nil, WithCommandsTimeout(time.Second))
+		if p.commandsTimeout != time.Second {
+			t.Errorf("expected commands timeout to be %v, got %v", time.Second, p.commandsTimeout)
+		}
+	})
+
+	t.Run("command concurrency", func(t *testing.T) {
+		p := NewProgram(nil, WithCommandConcurrency(4))
+		if p.scheduler.limit != 4 {
+			t.Errorf("expected command concurrency to be 4, got %d", p.scheduler.limit)
+		}
+	})
+
 	t.Run("startup options", func(t *testing.T) {
 		exercise := func(t *testing.T, opt ProgramOption, expect startupOptions) {
 			p := NewProgram(nil, opt)
@@ -46,6 +69,10 @@ func TestOptions(t *testing.T) {
 			exercise(t, WithoutSignalHandler(), withoutSignalHandler)
 		})
 
+		t.Run("without frame coalescing", func(t *testing.T) {
+			exercise(t, WithoutFrameCoalescing(), withoutFrameCoalescing)
+		})
+
 	})
 
 }
@@ -0,0 +1,196 @@
+package masc
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Store is a storage backend for a persisted model, see WithPersistence.
+type Store interface {
+	// Load returns the stored data, or nil if nothing has been stored yet.
+	Load() ([]byte, error)
+
+	// Save replaces the stored data.
+	Save(data []byte) error
+}
+
+// Codec serializes models for a Store.
+type Codec interface {
+	// Marshal encodes model.
+	Marshal(model Model) ([]byte, error)
+
+	// Unmarshal decodes data into model, the program's initial model, and
+	// returns the restored model.
+	Unmarshal(data []byte, model Model) (Model, error)
+}
+
+// JSONCodec is a Codec that encodes models with encoding/json. Only exported
+// fields are persisted. The model passed to NewProgram must be a pointer so
+// that it can be decoded into.
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(model Model) ([]byte, error) {
+	return json.Marshal(model)
+}
+
+func (jsonCodec) Unmarshal(data []byte, model Model) (Model, error) {
+	if err := json.Unmarshal(data, model); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// PersistErrorMsg is sent to the model when its state cannot be restored or
+// saved by the Store configured with WithPersistence.
+type PersistErrorMsg struct {
+	Err error
+}
+
+// persistInterval is the minimum time between saves of a persisted model.
+const persistInterval = 250 * time.Millisecond
+
+// persistMsg tells the event loop that a throttled save of the model is due.
+type persistMsg struct{}
+
+// persistence saves a program's model to a Store.
+type persistence struct {
+	store Store
+	codec Codec
+
+	// scheduled is set while a save is due, so that the updates in between
+	// share it. It is only accessed from the event loop.
+	scheduled bool
+
+	// mtx serializes saves, which run outside the event loop, and guards the
+	// fields below.
+	mtx sync.Mutex
+
+	// seq numbers saves in the order the model was encoded, and saved is the
+	// number of the latest save written, so that an older save never
+	// overwrites a newer one.
+	seq, saved uint64
+
+	// last is the data of the latest save, or of the restored model. A model
+	// that encodes to the same data is not saved again.
+	last []byte
+}
+
+// restore returns model updated with the stored state, or model itself if
+// nothing was stored. If the stored state cannot be decoded, model is returned
+// with the error.
+func (s *persistence) restore(model Model) (Model, error) {
+	data, err := s.store.Load()
+	if err != nil || data == nil {
+		return model, err
+	}
+	restored, err := s.codec.Unmarshal(data, model)
+	if err != nil {
+		return model, err
+	}
+	if last, err := s.codec.Marshal(restored); err == nil {
+		s.last = last
+	}
+	return restored, nil
+}
+
+// pending reports whether saves have been started that are not written yet.
+func (s *persistence) pending() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.saved < s.seq
+}
+
+// schedulePersist arranges for the model to be saved once persistInterval has
+// passed, unless a save is already due. It is called after Update.
+func (p *Program) schedulePersist() {
+	s := p.persistence
+	if s.scheduled {
+		return
+	}
+	s.scheduled = true
+	p.exec(func() {
+		if _, ok := sleep(p.ctx, p.clock, persistInterval); ok {
+			p.deliver(persistMsg{})
+		}
+	})
+}
+
+// persist saves model if it changed since the last save. It is called from
+// the event loop, which owns the model, so the model is encoded there and
+// written in the background, unless wait is set. Saves are written in order;
+// one that finds a newer save written is dropped. With wait, persist also
+// waits for the save being written, if any, so that the final save is the
+// last one.
+func (p *Program) persist(model Model, wait bool) {
+	s := p.persistence
+	s.scheduled = false
+	data, err := s.codec.Marshal(model)
+	if err != nil {
+		if !wait {
+			p.exec(func() { p.deliver(PersistErrorMsg{Err: err}) })
+		}
+		return
+	}
+
+	s.mtx.Lock()
+	if bytes.Equal(data, s.last) && (!wait || s.saved == s.seq) {
+		s.mtx.Unlock()
+		return
+	}
+	s.seq++
+	seq := s.seq
+	s.last = data
+	s.mtx.Unlock()
+
+	save := func() error {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		if seq < s.saved {
+			return nil
+		}
+		s.saved = seq
+		err := s.store.Save(data)
+		if err != nil && seq == s.seq {
+			// Save the same model again after the next update.
+			s.last = nil
+		}
+		return err
+	}
+	if wait {
+		save() //nolint:errcheck
+		return
+	}
+	p.exec(func() {
+		if err := save(); err != nil {
+			p.deliver(PersistErrorMsg{Err: err})
+		}
+	})
+}
+
+// MemoryStore returns a Store that keeps the data in memory, which is mostly
+// useful in tests.
+func MemoryStore() Store {
+	return &memoryStore{}
+}
+
+type memoryStore struct {
+	mtx  sync.Mutex
+	data []byte
+}
+
+func (s *memoryStore) Load() ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]byte(nil), s.data...), nil
+}
+
+func (s *memoryStore) Save(data []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.data = append([]byte(nil), data...)
+	return nil
+}
@@ -0,0 +1,38 @@
+//go:build js
+// +build js
+
+package masc
+
+// LocalStorage returns a Store that keeps the data in the browser's
+// localStorage under key, so that it survives page reloads and browser
+// restarts.
+func LocalStorage(key string) Store {
+	return &webStorage{storage: "localStorage", key: key}
+}
+
+// SessionStorage returns a Store that keeps the data in the browser's
+// sessionStorage under key, so that it survives page reloads but not the end
+// of the browsing session.
+func SessionStorage(key string) Store {
+	return &webStorage{storage: "sessionStorage", key: key}
+}
+
+// webStorage implements Store with the Web Storage API.
+type webStorage struct {
+	storage string
+	key     string
+}
+
+func (s *webStorage) Load() ([]byte, error) {
+	item := global().Get(s.storage).Call("getItem", s.key)
+	if !item.Truthy() {
+		// getItem returns null for missing keys.
+		return nil, nil
+	}
+	return []byte(item.String()), nil
+}
+
+func (s *webStorage) Save(data []byte) error {
+	global().Get(s.storage).Call("setItem", s.key, string(data))
+	return nil
+}
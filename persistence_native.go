@@ -0,0 +1,44 @@
+//go:build !js
+// +build !js
+
+package masc
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileStore returns a Store that keeps the data in the file at path. The file
+// is replaced atomically on every save.
+func FileStore(path string) Store {
+	return fileStore(path)
+}
+
+type fileStore string
+
+func (path fileStore) Load() ([]byte, error) {
+	data, err := os.ReadFile(string(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (path fileStore) Save(data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(string(path)), filepath.Base(string(path))+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), string(path))
+}
@@ -0,0 +1,168 @@
+package masc
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type persistedModel struct {
+	Core
+	N    int
+	errs []error
+	done chan struct{}
+}
+
+func (m *persistedModel) Init() Cmd { return nil }
+
+func (m *persistedModel) Update(msg Msg) (Model, Cmd) {
+	switch msg := msg.(type) {
+	case incrementMsg:
+		m.N++
+	case PersistErrorMsg:
+		m.errs = append(m.errs, msg.Err)
+		close(m.done)
+	}
+	return m, nil
+}
+
+func (m *persistedModel) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+func TestPersistence(t *testing.T) {
+	store := MemoryStore()
+	if err := store.Save([]byte(`{"N":5}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &persistedModel{}
+	p := NewProgram(m, WithoutRenderer(), WithoutFrameCoalescing(), WithPersistence(store, nil))
+	go func() {
+		p.Send(incrementMsg{})
+		p.Send(incrementMsg{})
+		p.Quit()
+	}()
+	if _, err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if m.N != 7 {
+		t.Fatalf("expected the restored model to be incremented to 7, got %d", m.N)
+	}
+
+	data, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"N":7}` {
+		t.Fatalf("expected the final model to be saved, got %s", data)
+	}
+}
+
+func TestPersistenceRestoreError(t *testing.T) {
+	store := MemoryStore()
+	if err := store.Save([]byte(`not json`)); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &persistedModel{done: make(chan struct{})}
+	p := NewProgram(m, WithoutRenderer(), WithoutFrameCoalescing(), WithPersistence(store, nil))
+	go func() {
+		<-m.done
+		p.Quit()
+	}()
+	if _, err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.errs) != 1 || m.N != 0 {
+		t.Fatalf("expected a restore error and the initial model, got %v and N=%d", m.errs, m.N)
+	}
+}
+
+// recordingStore is a MemoryStore that records the data it saves. The first
+// save waits for release to be closed, if it is set.
+type recordingStore struct {
+	Store
+	release chan struct{}
+
+	mtx   sync.Mutex
+	saves []string
+}
+
+func (s *recordingStore) Save(data []byte) error {
+	s.mtx.Lock()
+	first := len(s.saves) == 0
+	s.saves = append(s.saves, string(data))
+	s.mtx.Unlock()
+	if first && s.release != nil {
+		<-s.release
+	}
+	return s.Store.Save(data)
+}
+
+func TestPersistenceUnchanged(t *testing.T) {
+	store := &recordingStore{Store: MemoryStore()}
+	if err := store.Store.Save([]byte(`{"N":5}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &persistedModel{}
+	p := NewProgram(m, WithoutRenderer(), WithoutFrameCoalescing(), WithPersistence(store, nil))
+	go func() {
+		p.Send("ignored")
+		p.Quit()
+	}()
+	if _, err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.saves) != 0 {
+		t.Fatalf("expected the unchanged model not to be saved, got %v", store.saves)
+	}
+}
+
+// TestPersistenceOrder tests that an older save never overwrites a newer one,
+// and that the final save waits for the save being written.
+func TestPersistenceOrder(t *testing.T) {
+	store := &recordingStore{Store: MemoryStore(), release: make(chan struct{})}
+	m := &persistedModel{}
+	p := NewProgram(m, WithoutRenderer(), WithPersistence(store, nil))
+	for i := 1; i <= 3; i++ {
+		m.N = i
+		p.persist(m, false)
+	}
+	close(store.release)
+	m.N = 4
+	p.persist(m, true)
+
+	data, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"N":4}` {
+		t.Fatalf("expected the final model to be saved last, got %s", data)
+	}
+	p.inflight.Wait()
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+	if last := store.saves[len(store.saves)-1]; last != `{"N":4}` {
+		t.Fatalf("expected no save after the final one, got %v", store.saves)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store := FileStore(filepath.Join(t.TempDir(), "model.json"))
+	data, err := store.Load()
+	if err != nil || data != nil {
+		t.Fatalf("expected no data before saving, got %q, %v", data, err)
+	}
+	if err := store.Save([]byte("saved")); err != nil {
+		t.Fatal(err)
+	}
+	data, err = store.Load()
+	if err != nil || string(data) != "saved" {
+		t.Fatalf("expected saved data, got %q, %v", data, err)
+	}
+
+	bad := FileStore(filepath.Join(t.TempDir(), "missing", "model.json"))
+	if err := bad.Save([]byte("saved")); err == nil {
+		t.Fatal("expected an error saving into a missing directory")
+	}
+}
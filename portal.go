@@ -0,0 +1,105 @@
+package masc
+
+// portal holds the children of a Portal, which are rendered into the target
+// node rather than the element the Portal is a child of.
+type portal struct {
+	// target is a CSS selector string or a DOM node.
+	target interface{}
+	// container stands in for the target node as the parent of the children.
+	container *HTML
+	// closed is set once the children have been removed, or handed over to
+	// the portal of the next render.
+	closed bool
+}
+
+// Portal returns HTML that renders children into target, rather than into the
+// element the Portal is a child of, to escape the overflow and stacking context
+// of its ancestors, e.g. for modals, tooltips and dropdowns. Target is either a
+// CSS selector, of which the first matching element is used, or a DOM node.
+// The children are appended to the target.
+//
+// In the element the Portal is a child of, it renders as a noscript tag. The
+// children otherwise behave as children of that element: Mounter and Unmounter
+// components are mounted and unmounted with it, and removing the Portal from
+// the render removes the children from the target.
+//
+//	elem.Div(
+//		masc.Text("Content"),
+//		masc.If(m.showModal, masc.Portal("#modals", &Modal{})),
+//	)
+func Portal(target interface{}, children ...ComponentOrHTML) *HTML {
+	return &HTML{
+		tag: "noscript",
+		portal: &portal{
+			target:    target,
+			container: &HTML{children: children},
+		},
+	}
+}
+
+// reconcilePortal reconciles the children of the portal of h, if any, against
+// those of the portal of prev, and removes the children of the portal of prev
+// if h has no portal into the same target.
+func (h *HTML) reconcilePortal(prev *HTML, send func(Msg), batch *batchRenderer) []Mounter {
+	var prevContainer *HTML
+	if prev.portal != nil && !prev.portal.closed {
+		prevContainer = prev.portal.container
+	}
+	if h.portal == nil {
+		if prevContainer != nil {
+			prev.portal.close()
+		}
+		return nil
+	}
+
+	// The children of portals are not server-rendered.
+	if batch.hydrating() {
+		defer batch.hydration.suspend()()
+	}
+
+	c := h.portal.container
+	c.node = h.portal.resolve(prev.portal)
+	if prevContainer != nil && !c.node.Equal(prevContainer.node) {
+		// The target changed, so start afresh in the new one.
+		prev.portal.close()
+		prevContainer = nil
+	}
+	if prevContainer == nil {
+		prevContainer = &HTML{node: c.node}
+	} else {
+		prev.portal.closed = true
+	}
+	return c.reconcileChildren(prevContainer, send, batch)
+}
+
+// resolve returns the target node of the portal, reusing that of prev if it
+// has the same selector.
+func (p *portal) resolve(prev *portal) jsObject {
+	selector, ok := p.target.(string)
+	if !ok {
+		if node, ok := portalNode(p.target); ok {
+			return node
+		}
+		panic("masc: Portal target must be a CSS selector or a DOM node")
+	}
+	if prev != nil && !prev.closed && prev.target == selector {
+		return prev.container.node
+	}
+	node := global().Get("document").Call("querySelector", selector)
+	if !node.Truthy() {
+		panic("masc: Portal target " + selector + " matches no element")
+	}
+	return node
+}
+
+// close removes the children of the portal from its target, unmounting them.
+func (p *portal) close() {
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.container.removeChildren(p.container.children)
+	for _, child := range p.container.children {
+		unmount(child)
+	}
+}
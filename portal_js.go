@@ -0,0 +1,14 @@
+//go:build js
+// +build js
+
+package masc
+
+import "syscall/js"
+
+// portalNode returns target as a node, if it is a js.Value.
+func portalNode(target interface{}) (jsObject, bool) {
+	if v, ok := target.(js.Value); ok {
+		return wrapObject(v), true
+	}
+	return nil, false
+}
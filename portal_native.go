@@ -0,0 +1,12 @@
+//go:build !js
+// +build !js
+
+package masc
+
+// portalNode returns target as a node, if it is a SyscallJSValue.
+func portalNode(target interface{}) (jsObject, bool) {
+	if v, ok := target.(jsObject); ok {
+		return v, true
+	}
+	return nil, false
+}
@@ -0,0 +1,91 @@
+package masc
+
+import (
+	"fmt"
+	"testing"
+)
+
+// lifecycleComponent counts its mounts and unmounts.
+type lifecycleComponent struct {
+	Core
+	mounts, unmounts *int
+}
+
+func (c *lifecycleComponent) Render(send func(Msg)) ComponentOrHTML { return Tag("p") }
+
+func (c *lifecycleComponent) Mount() { *c.mounts++ }
+
+func (c *lifecycleComponent) Unmount() { *c.unmounts++ }
+
+// TestPortal tests that the children of a Portal are rendered into its target,
+// and are mounted, unmounted and removed with the Portal.
+func TestPortal(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.strings.mock(`global.Get("document").Get("readyState")`, "complete")
+	ts.strings.mock(`global.Get("document").Call("querySelector", "body").Get("nodeName")`, "BODY")
+	ts.truthies.mock(`global.Get("document").Call("querySelector", "body")`, true)
+	ts.truthies.mock(`global.Get("document").Call("querySelector", "#modals")`, true)
+
+	var (
+		show             = true
+		title            = "a"
+		mounts, unmounts int
+		clicks           []*EventListener
+	)
+	comp := &componentFunc{
+		render: func() ComponentOrHTML {
+			var modal ComponentOrHTML
+			if show {
+				click := &EventListener{Name: "click"}
+				clicks = append(clicks, click)
+				modal = Portal("#modals",
+					Tag("h1", Markup(click), Text(title)),
+					&lifecycleComponent{mounts: &mounts, unmounts: &unmounts},
+				)
+			}
+			return Tag("body", Tag("main", modal))
+		},
+		skipRender: func(prev Component) bool { return false },
+	}
+
+	batch := newBatchRenderer(nil)
+	renderBody(batch, comp, send)
+	ts.record(fmt.Sprintf("// mounts %d, unmounts %d", mounts, unmounts))
+
+	step := func(name string) {
+		ts.record("// " + name)
+		rerender(batch, comp, send)
+		ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+		ts.invokeCallbackRequestAnimationFrame(0)
+		ts.record(fmt.Sprintf("// mounts %d, unmounts %d", mounts, unmounts))
+	}
+
+	title = "b"
+	step("update")
+
+	wrapper := clicks[len(clicks)-1].wrapper.(*jsFuncImpl)
+	show = false
+	step("remove")
+	if !wrapper.released {
+		t.Fatal("event listener of portal child not released")
+	}
+}
+
+// TestPortal_node tests rendering a Portal into a DOM node.
+func TestPortal_node(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	target := global().Get("document").Call("getElementById", "modals")
+	h := Tag("main", Portal(target, Tag("h1")))
+	h.reconcile(nil, send, nil)
+	unmount(h)
+
+	got := recoverStr(func() {
+		Tag("main", Portal(1)).reconcile(nil, send, nil)
+	})
+	ts.record("// " + got)
+}
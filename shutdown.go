@@ -0,0 +1,103 @@
+package masc
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// drainMsg tells the event loop that the program is shutting down, so that
+// it stops the subscriptions and starts no more commands.
+type drainMsg struct{}
+
+// shutdownMsg tells the event loop to exit once the program has drained.
+// Unlike QuitMsg, it does not go through the middleware.
+type shutdownMsg struct{}
+
+// Shutdown stops the program gracefully. Messages passed to Send from then
+// on are dropped, subscriptions are stopped and no new commands are started,
+// while the commands in flight finish and deliver their messages. Once they
+// have, or when ctx is done, the program exits as if it had quit: the final
+// model is rendered and saved if it is persisted, and the OnShutdown hooks
+// are run. Shutdown returns after Run has returned.
+//
+// Commands are in flight from the moment Update returns them, including those
+// that wait for the next animation frame or for a free slot, see
+// WithCommandConcurrency.
+//
+// Shutdown returns ctx.Err() if commands were still running when ctx was
+// done, and ErrProgramNotStarted if Run has not been called. Calling Shutdown
+// again, or after the program has exited, waits for Run to return and returns
+// nil.
+//
+// Shutdown must not be called from Update, from a command or from an
+// OnShutdown hook, since it would wait for itself: Update and the hooks run
+// before Run returns, and commands are waited for. Call it in a new goroutine
+// there instead.
+//
+// In js builds, the program is shut down when the page is unloaded, on the
+// pagehide event, unless the page is kept in the back/forward cache. The
+// browser does not wait for asynchronous work then, so in-flight commands are
+// not waited for, but the final model is still saved and the hooks run.
+func (p *Program) Shutdown(ctx context.Context) error {
+	if atomic.LoadUint32(&p.started) == 0 {
+		return ErrProgramNotStarted
+	}
+	if !atomic.CompareAndSwapUint32(&p.draining, 0, 1) {
+		<-p.stopped
+		return nil
+	}
+
+	// Stop the subscriptions, which are in flight until they are stopped.
+	p.deliver(drainMsg{})
+
+	drained := make(chan struct{})
+	go func() {
+		p.inflight.Wait()
+		close(drained)
+	}()
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	p.deliver(shutdownMsg{})
+	<-p.stopped
+	return err
+}
+
+// OnShutdown registers hook to be called with the final model when the
+// program exits, after the model has been saved if it is persisted. Hooks
+// run in the order they were registered, whether the program quit, was shut
+// down or was killed.
+func (p *Program) OnShutdown(hook func(model Model)) {
+	p.hooksMtx.Lock()
+	defer p.hooksMtx.Unlock()
+	p.shutdownHooks = append(p.shutdownHooks, hook)
+}
+
+// runShutdownHooks calls the OnShutdown hooks with model.
+func (p *Program) runShutdownHooks(model Model) {
+	p.hooksMtx.Lock()
+	hooks := append([]func(Model){}, p.shutdownHooks...)
+	p.hooksMtx.Unlock()
+	for _, hook := range hooks {
+		hook(model)
+	}
+}
+
+// deliver passes msg, which is not from a new source but the result of work
+// already under way, to the event loop. Unlike Send, it still delivers while
+// the program is shutting down.
+func (p *Program) deliver(msg Msg) {
+	select {
+	case <-p.ctx.Done():
+	case p.msgs <- msg:
+	}
+}
+
+// shuttingDown reports whether Shutdown has been called.
+func (p *Program) shuttingDown() bool {
+	return atomic.LoadUint32(&p.draining) == 1
+}
@@ -0,0 +1,36 @@
+//go:build js
+// +build js
+
+package masc
+
+import "context"
+
+// shutdownOnUnload shuts the program down when the page is unloaded. The
+// returned function removes the listener.
+//
+// It listens for pagehide rather than beforeunload, which also fires when the
+// navigation is then cancelled, and ignores pages that are kept in the
+// back/forward cache, which may be shown again.
+func (p *Program) shutdownOnUnload() func() {
+	cb := funcOf(func(_ jsObject, args []jsObject) interface{} {
+		if len(args) > 0 && args[0].Get("persisted").Truthy() {
+			return nil
+		}
+		// The browser does not wait for asynchronous work, so don't wait
+		// for in-flight commands. The listener doesn't wait for Shutdown
+		// either: the Go runtime runs the other goroutines until they
+		// block before it hands control back to the browser, which lets
+		// the program save its final model and run its hooks, unless
+		// they wait on the browser themselves.
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		go p.Shutdown(ctx) //nolint:errcheck
+		return nil
+	})
+	window := global()
+	window.Call("addEventListener", "pagehide", cb)
+	return func() {
+		window.Call("removeEventListener", "pagehide", cb)
+		cb.Release()
+	}
+}
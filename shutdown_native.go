@@ -0,0 +1,10 @@
+//go:build !js
+// +build !js
+
+package masc
+
+// shutdownOnUnload does nothing: there is no page to unload outside the
+// browser.
+func (p *Program) shutdownOnUnload() func() {
+	return func() {}
+}
@@ -0,0 +1,177 @@
+package masc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type shutdownModel struct {
+	Core
+	Msgs    []string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *shutdownModel) Init() Cmd {
+	return func() Msg {
+		close(m.started)
+		<-m.release
+		return "saved"
+	}
+}
+
+func (m *shutdownModel) Update(msg Msg) (Model, Cmd) {
+	switch msg := msg.(type) {
+	case string:
+		m.Msgs = append(m.Msgs, msg)
+	case incrementMsg:
+		m.Msgs = append(m.Msgs, "increment")
+	}
+	return m, nil
+}
+
+func (m *shutdownModel) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+func (m *shutdownModel) Subscriptions() []Sub {
+	return []Sub{{Key: "sub", Run: func(ctx context.Context, send func(Msg)) { <-ctx.Done() }}}
+}
+
+func TestShutdown(t *testing.T) {
+	store := MemoryStore()
+	m := &shutdownModel{started: make(chan struct{}), release: make(chan struct{})}
+	p := NewProgram(m, WithoutRenderer(), WithoutFrameCoalescing(), WithPersistence(store, nil))
+	var hooks []string
+	p.OnShutdown(func(model Model) {
+		hooks = append(hooks, "first")
+		if model != m {
+			t.Errorf("expected the hook to get the final model, got %v", model)
+		}
+	})
+	p.OnShutdown(func(Model) { hooks = append(hooks, "second") })
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := p.Run()
+		errs <- err
+	}()
+	<-m.started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- p.Shutdown(context.Background()) }()
+	waitFor(t, p.shuttingDown)
+	p.Send(incrementMsg{})
+	close(m.release)
+
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Msgs, []string{"saved"}) {
+		t.Fatalf("expected only the in-flight command's message, got %v", m.Msgs)
+	}
+	if !reflect.DeepEqual(hooks, []string{"first", "second"}) {
+		t.Fatalf("expected the hooks to run in order, got %v", hooks)
+	}
+	data, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"Msgs":["saved"]}` {
+		t.Fatalf("expected the final model to be saved, got %s", data)
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("expected a second Shutdown to return nil, got %v", err)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	m := &shutdownModel{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(m.release)
+	p := NewProgram(m, WithoutRenderer(), WithoutFrameCoalescing())
+	var hooked bool
+	p.OnShutdown(func(Model) { hooked = true })
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := p.Run()
+		errs <- err
+	}()
+	<-m.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the deadline to be exceeded, got %v", err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if !hooked || len(m.Msgs) != 0 {
+		t.Fatalf("expected the hooks to run without the command's message, got %v and %v", hooked, m.Msgs)
+	}
+}
+
+// pendingCmdModel returns a command that waits for release on every
+// incrementMsg.
+type pendingCmdModel struct {
+	Core
+	Msgs    []string
+	release chan struct{}
+}
+
+func (m *pendingCmdModel) Init() Cmd { return nil }
+
+func (m *pendingCmdModel) Update(msg Msg) (Model, Cmd) {
+	switch msg := msg.(type) {
+	case string:
+		m.Msgs = append(m.Msgs, msg)
+	case incrementMsg:
+		return m, func() Msg {
+			<-m.release
+			return "done"
+		}
+	}
+	return m, nil
+}
+
+func (m *pendingCmdModel) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+// TestShutdownPendingCmd tests that Shutdown waits for the command returned by
+// the last update, even if it has not started yet.
+func TestShutdownPendingCmd(t *testing.T) {
+	m := &pendingCmdModel{release: make(chan struct{})}
+	p := NewProgram(m, WithoutRenderer())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := p.Run()
+		errs <- err
+	}()
+	p.Send(incrementMsg{})
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- p.Shutdown(context.Background()) }()
+	waitFor(t, p.shuttingDown)
+	close(m.release)
+
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Msgs, []string{"done"}) {
+		t.Fatalf("expected the pending command's message, got %v", m.Msgs)
+	}
+}
+
+func TestShutdownNotStarted(t *testing.T) {
+	p := NewProgram(&pendingCmdModel{}, WithoutRenderer())
+	if err := p.Shutdown(context.Background()); err != ErrProgramNotStarted {
+		t.Fatalf("expected ErrProgramNotStarted, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package masc
+
+// Snapshotter is implemented by models that keep their state across a hot
+// reload by masc serve. When the app is rebuilt, the dev server asks the
+// running program for a snapshot of its model before it reloads the page, and
+// the new build restores the model from it in place of its initial model.
+//
+// Snapshot and Restore are only used during development, so the encoding does
+// not need to be stable between releases. Fields added or removed between
+// builds should be tolerated, though, as the snapshot is taken by the old
+// build and restored by the new one.
+type Snapshotter interface {
+	// Snapshot encodes the model's state.
+	Snapshot() ([]byte, error)
+
+	// Restore decodes data, as returned by Snapshot, and returns the restored
+	// model. It is called on the program's initial model.
+	Restore(data []byte) (Model, error)
+}
+
+// snapshotMsg asks the event loop for a snapshot of the model.
+type snapshotMsg struct {
+	reply chan<- snapshotResult
+}
+
+type snapshotResult struct {
+	data []byte
+	err  error
+}
+
+// takeSnapshot returns the snapshot of model, or nil if model is not a
+// Snapshotter.
+func takeSnapshot(model Model) snapshotResult {
+	s, ok := asSnapshotter(model)
+	if !ok {
+		return snapshotResult{}
+	}
+	data, err := s.Snapshot()
+	return snapshotResult{data: data, err: err}
+}
+
+// snapshot returns a snapshot of the running program's model, taken by the
+// event loop. It returns nil if the model is not a Snapshotter.
+func (p *Program) snapshot() ([]byte, error) {
+	reply := make(chan snapshotResult, 1)
+	select {
+	case p.msgs <- snapshotMsg{reply: reply}:
+	case <-p.ctx.Done():
+		return nil, ErrProgramKilled
+	}
+	select {
+	case r := <-reply:
+		return r.data, r.err
+	case <-p.ctx.Done():
+		return nil, ErrProgramKilled
+	}
+}
+
+// restoreSnapshot returns model restored from data, or model itself if there
+// is no snapshot or model is not a Snapshotter.
+func restoreSnapshot(model Model, data []byte) (Model, error) {
+	s, ok := asSnapshotter(model)
+	if !ok || data == nil {
+		return model, nil
+	}
+	restored, err := s.Restore(data)
+	if err != nil {
+		return model, err
+	}
+	return restored, nil
+}
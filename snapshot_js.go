@@ -0,0 +1,61 @@
+//go:build js
+// +build js
+
+package masc
+
+import "encoding/base64"
+
+const (
+	// snapshotKey is the sessionStorage key under which the masc serve page
+	// stashes the model's snapshot while it reloads.
+	snapshotKey = "masc:snapshot"
+
+	// snapshotHook is the global function through which the masc serve page
+	// asks the running program for a snapshot.
+	snapshotHook = "__mascSnapshot"
+)
+
+// loadSnapshot returns the snapshot stashed before a hot reload, if any. It is
+// removed from the storage, so that reloading the page by hand starts afresh.
+func loadSnapshot() []byte {
+	storage := global().Get("sessionStorage")
+	item := storage.Call("getItem", snapshotKey)
+	if !item.Truthy() {
+		return nil
+	}
+	storage.Call("removeItem", snapshotKey)
+	data, err := base64.StdEncoding.DecodeString(item.String())
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// serveSnapshots exposes snapshots of the program's model to the masc serve
+// page while the program runs. The returned function withdraws them.
+func (p *Program) serveSnapshots() func() {
+	if _, ok := asSnapshotter(p.initialModel); !ok {
+		return func() {}
+	}
+	cb := funcOf(func(jsObject, []jsObject) interface{} {
+		data, err := p.snapshot()
+		if err != nil {
+			global().Get("console").Call("warn", "masc: cannot snapshot model:", err.Error())
+			return nil
+		}
+		if data == nil {
+			return nil
+		}
+		return base64.StdEncoding.EncodeToString(data)
+	})
+	global().Set(snapshotHook, cb)
+	return func() {
+		global().Delete(snapshotHook)
+		cb.Release()
+	}
+}
+
+// warnRestore reports a snapshot that could not be restored.
+func warnRestore(err error) {
+	global().Get("console").Call("warn", "masc: cannot restore snapshot:", err.Error())
+}
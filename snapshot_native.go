@@ -0,0 +1,16 @@
+//go:build !js
+// +build !js
+
+package masc
+
+// loadSnapshot returns nil: hot reload is only supported in the browser.
+func loadSnapshot() []byte {
+	return nil
+}
+
+// serveSnapshots does nothing: hot reload is only supported in the browser.
+func (p *Program) serveSnapshots() func() {
+	return func() {}
+}
+
+func warnRestore(error) {}
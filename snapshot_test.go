@@ -0,0 +1,66 @@
+package masc
+
+import (
+	"strconv"
+	"testing"
+)
+
+type snapshotModel struct {
+	persistedModel
+}
+
+func (m *snapshotModel) Update(msg Msg) (Model, Cmd) {
+	m.persistedModel.Update(msg)
+	return m, nil
+}
+
+func (m *snapshotModel) Snapshot() ([]byte, error) {
+	return []byte(strconv.Itoa(m.N)), nil
+}
+
+func (m *snapshotModel) Restore(data []byte) (Model, error) {
+	n, err := strconv.Atoi(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotModel{persistedModel{N: n}}, nil
+}
+
+func TestSnapshot(t *testing.T) {
+	p := NewProgram(&snapshotModel{}, WithoutRenderer(), WithoutFrameCoalescing())
+	snapshots := make(chan string, 1)
+	go func() {
+		p.Send(incrementMsg{})
+		p.Send(incrementMsg{})
+		data, err := p.snapshot()
+		if err != nil {
+			t.Error(err)
+		}
+		snapshots <- string(data)
+		p.Quit()
+	}()
+	if _, err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	data := <-snapshots
+	if data != "2" {
+		t.Fatalf("expected a snapshot of the updated model, got %q", data)
+	}
+
+	restored, err := restoreSnapshot(&snapshotModel{}, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := restored.(*snapshotModel).N; n != 2 {
+		t.Fatalf("expected the restored model to have N=2, got %d", n)
+	}
+
+	initial := &snapshotModel{}
+	restored, err = restoreSnapshot(initial, []byte("not a number"))
+	if err == nil || restored != initial {
+		t.Fatalf("expected an error and the initial model, got %v and %v", err, restored)
+	}
+	if restored, _ := restoreSnapshot(&persistedModel{}, []byte("2")); restored.(*persistedModel).N != 0 {
+		t.Fatal("expected a model that is not a Snapshotter to be left alone")
+	}
+}
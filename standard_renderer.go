@@ -1,6 +1,7 @@
 package masc
 
 import (
+	"errors"
 	"sync"
 )
 
@@ -9,22 +10,27 @@ type standardRenderer struct {
 	rootNode jsObject
 	rendered bool
 
+	// batch queues the rerenders of this renderer's components.
+	batch *batchRenderer
+
 	mtx *sync.Mutex
 }
 
 // newRenderer creates a new renderer. Normally you'll want to initialize it
 // with os.Stdout as the first argument.
-func newRenderer() renderer {
+func newRenderer(p *Program) renderer {
 	r := &standardRenderer{
-		mtx: &sync.Mutex{},
+		mtx:   &sync.Mutex{},
+		batch: newBatchRenderer(p),
 	}
 	return r
 }
 
-func newNodeRenderer(node jsObject) renderer {
+func newNodeRenderer(p *Program, node jsObject) renderer {
 	r := &standardRenderer{
 		mtx:      &sync.Mutex{},
 		rootNode: node,
+		batch:    newBatchRenderer(p),
 	}
 	return r
 }
@@ -42,16 +48,38 @@ func (r *standardRenderer) render(c Component, send func(Msg)) {
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
 	if r.rendered {
-		rerender(c, send)
+		rerender(r.batch, c, send)
 		return
 	}
 	r.rendered = true
+	if p := r.batch.program; p != nil && p.startupOptions.has(withHydration) {
+		r.hydrate(c, send)
+		return
+	}
 	if !isZeroValue(r.rootNode) {
-		err := renderIntoNode("RenderIntoNode", r.rootNode, c, send)
+		err := renderIntoNode("RenderIntoNode", r.rootNode, c, send, r.batch)
 		if err != nil {
 			panic(err)
 		}
 	} else {
-		RenderBody(c, send)
+		renderBody(r.batch, c, send)
+	}
+}
+
+// hydrate renders c for the first time, adopting the server-rendered nodes of
+// the root node, or of the body.
+func (r *standardRenderer) hydrate(c Component, send func(Msg)) {
+	method, target := "RenderIntoNode", r.rootNode
+	if isZeroValue(target) {
+		method, target = "RenderBody", global().Get("document").Call("querySelector", "body")
+	}
+	err := hydrateIntoNode(method, target, c, send, r.batch)
+	var mismatch HydrationError
+	switch {
+	case errors.As(err, &mismatch):
+		p := r.batch.program
+		p.exec(func() { p.deliver(HydrationErrorMsg{Err: err}) })
+	case err != nil:
+		panic(err)
 	}
 }
@@ -0,0 +1,121 @@
+package masc
+
+import (
+	"context"
+	"time"
+)
+
+// Sub is a long-running source of messages, such as a timer, a websocket or a
+// browser event listener. Unlike a Cmd, which produces a single message, a Sub
+// keeps sending messages until the model stops asking for it.
+//
+// Subscriptions are identified by Key. The program compares keys after every
+// Update: subscriptions with new keys are started, subscriptions whose keys
+// disappeared are stopped, and subscriptions whose keys are still present keep
+// running untouched. Any parameter that should restart a subscription when it
+// changes, such as a URL or an interval, must therefore be part of its key.
+type Sub struct {
+	// Key uniquely identifies the subscription amongst the model's
+	// subscriptions. It must be a valid map key.
+	Key interface{}
+
+	// Run starts the subscription. It should send messages with send until
+	// ctx is cancelled, then release its resources and return.
+	Run func(ctx context.Context, send func(Msg))
+}
+
+// Subscriber is an optional interface that a Model can implement to declare
+// the subscriptions it needs. Subscriptions is called after Init and after
+// every Update.
+//
+// Example:
+//
+//	func (m model) Subscriptions() []masc.Sub {
+//	    if !m.polling {
+//	        return nil
+//	    }
+//	    return []masc.Sub{
+//	        masc.Interval("poll", 5*time.Second, func(t time.Time) masc.Msg {
+//	            return pollMsg(t)
+//	        }),
+//	    }
+//	}
+type Subscriber interface {
+	Subscriptions() []Sub
+}
+
+// Interval returns a subscription that sends the message returned by fn every
+// d until the subscription is stopped. It uses the program's Clock. Like
+// time.NewTicker, Interval panics if d is not positive.
+func Interval(key interface{}, d time.Duration, fn func(time.Time) Msg) Sub {
+	if d <= 0 {
+		panic("masc: non-positive interval for Interval")
+	}
+	return Sub{
+		Key: key,
+		Run: func(ctx context.Context, send func(Msg)) {
+			clock := ClockFromContext(ctx)
+			next := clock.Now().Add(d)
+			for {
+				now, ok := sleep(ctx, clock, next.Sub(clock.Now()))
+				if !ok {
+					return
+				}
+				send(fn(now))
+				next = next.Add(d)
+			}
+		},
+	}
+}
+
+// syncSubscriptions starts and stops subscriptions so that the running set
+// matches the one declared by model.
+func (p *Program) syncSubscriptions(model Model) {
+	var subs []Sub
+	if s, ok := model.(Subscriber); ok {
+		subs = s.Subscriptions()
+	}
+	if len(subs) == 0 && len(p.subs) == 0 {
+		return
+	}
+
+	wanted := make(map[interface{}]struct{}, len(subs))
+	for _, sub := range subs {
+		if _, exists := wanted[sub.Key]; exists {
+			panic("masc: duplicate subscription key")
+		}
+		wanted[sub.Key] = struct{}{}
+	}
+
+	// Stop subscriptions the model no longer wants.
+	for key, cancel := range p.subs {
+		if _, ok := wanted[key]; !ok {
+			cancel()
+			delete(p.subs, key)
+		}
+	}
+
+	// Start new subscriptions.
+	for _, sub := range subs {
+		if _, running := p.subs[sub.Key]; running || sub.Run == nil {
+			continue
+		}
+		if p.subs == nil {
+			p.subs = make(map[interface{}]context.CancelFunc)
+		}
+		ctx, cancel := context.WithCancel(p.ctx)
+		p.subs[sub.Key] = cancel
+		run := sub.Run
+		p.exec(func() {
+			run(ctx, p.Send)
+		})
+	}
+}
+
+// stopSubscriptions stops all running subscriptions.
+func (p *Program) stopSubscriptions() {
+	for key, cancel := range p.subs {
+		cancel()
+		delete(p.subs, key)
+	}
+}
@@ -0,0 +1,256 @@
+package masc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type setSubsMsg []interface{}
+
+type subTestModel struct {
+	Core
+	keys             []interface{}
+	started, stopped chan interface{}
+}
+
+func (m *subTestModel) Init() Cmd { return nil }
+
+func (m *subTestModel) Update(msg Msg) (Model, Cmd) {
+	if keys, ok := msg.(setSubsMsg); ok {
+		m.keys = keys
+	}
+	return m, nil
+}
+
+func (m *subTestModel) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+func (m *subTestModel) Subscriptions() []Sub {
+	var subs []Sub
+	for _, key := range m.keys {
+		key := key
+		subs = append(subs, Sub{
+			Key: key,
+			Run: func(ctx context.Context, send func(Msg)) {
+				m.started <- key
+				<-ctx.Done()
+				m.stopped <- key
+			},
+		})
+	}
+	return subs
+}
+
+func expectKey(t *testing.T, ch chan interface{}, want interface{}) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for %v", want)
+	}
+}
+
+func expectNoKey(t *testing.T, ch chan interface{}) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		t.Fatalf("unexpected %v", got)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	m := &subTestModel{
+		keys:    []interface{}{"a"},
+		started: make(chan interface{}, 10),
+		stopped: make(chan interface{}, 10),
+	}
+	p := NewProgram(m, WithoutRenderer(), WithCommandsTimeout(time.Second))
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := p.Run()
+		errs <- err
+	}()
+
+	expectKey(t, m.started, "a")
+
+	// Adding a subscription starts it and keeps the existing one running.
+	p.Send(setSubsMsg{"a", "b"})
+	expectKey(t, m.started, "b")
+	expectNoKey(t, m.stopped)
+
+	// Removing a subscription stops only that subscription.
+	p.Send(setSubsMsg{"b"})
+	expectKey(t, m.stopped, "a")
+	expectNoKey(t, m.started)
+
+	// Quitting stops the remaining subscriptions.
+	p.Quit()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	expectKey(t, m.stopped, "b")
+}
+
+func TestSubscriptionsDuplicateKey(t *testing.T) {
+	p := NewProgram(nil)
+	m := &subTestModel{keys: []interface{}{"a", "a"}}
+	got := recoverStr(func() {
+		p.syncSubscriptions(m)
+	})
+	want := "masc: duplicate subscription key"
+	if got != want {
+		t.Fatalf("got panic %q want %q", got, want)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgs := make(chan Msg, 1)
+	sub := Interval("tick", time.Millisecond, func(time.Time) Msg { return "tick" })
+	go sub.Run(ctx, func(msg Msg) {
+		select {
+		case msgs <- msg:
+		default:
+		}
+	})
+
+	select {
+	case msg := <-msgs:
+		if msg != "tick" {
+			t.Fatalf("expected a msg %v but got %v", "tick", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for tick")
+	}
+}
+
+func TestIntervalNonPositive(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		got := recoverStr(func() {
+			Interval("tick", d, func(time.Time) Msg { return "tick" })
+		})
+		if want := "masc: non-positive interval for Interval"; got != want {
+			t.Errorf("Interval(%v): got panic %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestParseKeyCombo(t *testing.T) {
+	tests := []struct {
+		combo string
+		want  keyCombo
+	}{
+		{"Escape", keyCombo{key: "escape"}},
+		{"ctrl+s", keyCombo{key: "s", ctrl: true}},
+		{"Ctrl+Shift+Z", keyCombo{key: "z", ctrl: true, shift: true}},
+		{"meta+alt+plus", keyCombo{key: "+", meta: true, alt: true}},
+	}
+	for _, tst := range tests {
+		if got := parseKeyCombo(tst.combo); got != tst.want {
+			t.Errorf("parseKeyCombo(%q) = %+v, want %+v", tst.combo, got, tst.want)
+		}
+	}
+}
+
+func TestOnlineStatus(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	var cb *jsFuncImpl
+	orig := funcOfImpl
+	funcOfImpl = func(fn func(this jsObject, args []jsObject) interface{}) jsFunc {
+		cb = orig(fn).(*jsFuncImpl)
+		return cb
+	}
+	defer func() { funcOfImpl = orig }()
+
+	event := &objectRecorder{ts: ts, name: "event"}
+	ts.strings.mock(`event.Get("type")`, "offline")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs := make(chan Msg, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		OnlineStatus().Run(ctx, func(msg Msg) { msgs <- msg })
+	}()
+
+	waitFor(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return ts.callbacks[`global.Call("addEventListener", "offline", func)`] != nil
+	})
+	cb.goFunc(undefined(), []jsObject{event})
+	if msg := <-msgs; msg != (OnlineMsg{Online: false}) {
+		t.Fatalf("expected an offline message, got %v", msg)
+	}
+
+	cancel()
+	<-done
+	if !cb.released {
+		t.Fatal("expected the listener to be released")
+	}
+}
+
+func TestOnlineStatusOrder(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	var cb *jsFuncImpl
+	orig := funcOfImpl
+	funcOfImpl = func(fn func(this jsObject, args []jsObject) interface{}) jsFunc {
+		cb = orig(fn).(*jsFuncImpl)
+		return cb
+	}
+	defer func() { funcOfImpl = orig }()
+
+	const n = 20
+	event := &objectRecorder{ts: ts, name: "event"}
+	var want []Msg
+	for i := 0; i < n; i++ {
+		online := i%2 == 1
+		if online {
+			ts.strings.mock(`event.Get("type")`, "online")
+		} else {
+			ts.strings.mock(`event.Get("type")`, "offline")
+		}
+		want = append(want, OnlineMsg{Online: online})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs := make(chan Msg)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		OnlineStatus().Run(ctx, func(msg Msg) { msgs <- msg })
+	}()
+
+	waitFor(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return ts.callbacks[`global.Call("addEventListener", "offline", func)`] != nil
+	})
+	// The program is slower than the browser: every event is dispatched
+	// before the first message is received.
+	for i := 0; i < n; i++ {
+		cb.goFunc(undefined(), []jsObject{event})
+	}
+	var got []Msg
+	for i := 0; i < n; i++ {
+		got = append(got, <-msgs)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected messages in event order %v, got %v", want, got)
+	}
+
+	cancel()
+	<-done
+}
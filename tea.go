@@ -6,12 +6,14 @@
 package masc
 
 import (
+	"container/heap"
 	"context"
 	"errors"
 	"fmt"
 	"runtime"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -20,19 +22,26 @@ import (
 // ErrProgramKilled is returned by [Program.Run] when the program got killed.
 var ErrProgramKilled = errors.New("program was killed")
 
-// currentProgram holds a reference to the currently running program for panic handling
-// Used in dom_js.go for JavaScript builds
-//
-//nolint:unused
-var currentProgram *Program
+// ErrProgramNotStarted is returned by [Program.Shutdown] when Run has not
+// been called.
+var ErrProgramNotStarted = errors.New("program was not started")
 
 // Yield pauses execution to allow the UI to update and remain responsive.
 // This should be called periodically during CPU-intensive computations
 // to prevent blocking the UI thread. It yields for approximately one
 // animation frame duration (~16ms) to ensure smooth rendering.
+//
+// Yield waits on the clock set by UseClock, as it has no program context.
+// Inside a CmdCtx, use YieldContext so that the program's Clock is respected.
 func Yield() {
+	YieldContext(context.Background())
+}
+
+// YieldContext is like Yield, but waits on the Clock carried by ctx and
+// returns early if ctx is cancelled.
+func YieldContext(ctx context.Context) {
 	runtime.Gosched()
-	time.Sleep(16 * time.Millisecond)
+	sleep(ctx, ClockFromContext(ctx), 16*time.Millisecond)
 }
 
 // Msg contain data from the result of a IO operation. Msgs trigger the update
@@ -82,6 +91,15 @@ const (
 	// feature is on by default.
 	withoutCatchPanics
 	withoutBracketedPaste
+	// Messages received within one animation frame are applied to the model
+	// before it is rendered once. When this is set, the model is rendered
+	// after every message instead.
+	withoutFrameCoalescing
+	// Panics in updates are recovered, keeping the last good model, and
+	// reported to the model with an UpdatePanicMsg.
+	withUpdateRecovery
+	// The first render adopts the server-rendered DOM, see Hydrate.
+	withHydration
 )
 
 // handlers manages series of channels returned by various processes. It allows
@@ -117,17 +135,62 @@ type Program struct {
 
 	ctx    context.Context
 	cancel context.CancelFunc
+	clock  Clock
 
 	msgs     chan Msg
 	errs     chan error
 	finished chan struct{}
 
+	// frames receives a value when a render scheduled for the next animation
+	// frame is due.
+	frames chan struct{}
+
 	renderer renderer
 
 	ignoreSignals uint32
 
 	filter       func(Model, Msg) Msg
+	middleware   []Middleware
 	panicHandler func(interface{})
+	debugger     *Debugger
+
+	// tracer receives timing events, see WithTracer, and cmdSeq numbers the
+	// commands reported to it. traceClock times the events: it is the system
+	// clock, which is performance.now() in js builds, rather than the
+	// program's Clock, so that the events take their real time under a fake
+	// one.
+	tracer     Tracer
+	traceClock Clock
+	cmdSeq     uint64
+
+	// inflight tracks running commands so that Run can optionally wait for
+	// them to finish before returning.
+	inflight        sync.WaitGroup
+	commandsTimeout time.Duration
+
+	// concurrency limits the number of commands running at once, and
+	// scheduler queues the commands over the limit.
+	concurrency int
+	scheduler   *scheduler
+
+	// subs holds the cancel functions of running subscriptions, by key.
+	subs map[interface{}]context.CancelFunc
+
+	persistence *persistence
+
+	// bridge lets JavaScript send messages to the program, see WithJSBridge.
+	bridge *jsBridge
+
+	// started is set to 1 by Run, and draining by Shutdown. exited is
+	// closed once Run has saved the final model and run the OnShutdown
+	// hooks, and stopped when Run returns.
+	started  uint32
+	draining uint32
+	exited   chan struct{}
+	stopped  chan struct{}
+
+	hooksMtx      sync.Mutex
+	shutdownHooks []func(Model)
 }
 
 // Quit is a special command that tells the Bubble Tea program to exit.
@@ -150,6 +213,9 @@ func NewProgram(model Model, opts ...ProgramOption) *Program {
 	p := &Program{
 		initialModel: model,
 		msgs:         make(chan Msg),
+		exited:       make(chan struct{}),
+		stopped:      make(chan struct{}),
+		traceClock:   systemClock{},
 		panicHandler: defaultPanicHandler, // Set default panic handler
 	}
 
@@ -163,14 +229,24 @@ func NewProgram(model Model, opts ...ProgramOption) *Program {
 	if p.ctx == nil {
 		p.ctx = context.Background()
 	}
+	// Commands and subscriptions find the program's clock, and the commands
+	// started by Replace, Debounce and Throttle, in their context.
+	if p.clock == nil {
+		p.clock = useClock()
+	}
+	p.ctx = ContextWithClock(p.ctx, p.clock)
+	p.ctx = context.WithValue(p.ctx, keyedCmdsKey{}, newKeyedCmds())
+
 	// Initialize context and teardown channel.
 	p.ctx, p.cancel = context.WithCancel(p.ctx)
 
+	p.scheduler = &scheduler{limit: p.concurrency, exec: p.exec}
+
 	return p
 }
 
-// handleCommands runs commands in a goroutine and sends the result to the
-// program's message channel.
+// handleCommands passes commands to the scheduler, which runs them and sends
+// the results to the program's message channel.
 func (p *Program) handleCommands(cmds chan Cmd) chan struct{} {
 	ch := make(chan struct{})
 
@@ -183,19 +259,16 @@ func (p *Program) handleCommands(cmds chan Cmd) chan struct{} {
 				return
 
 			case cmd := <-cmds:
-				if cmd == nil {
-					continue
+				// Don't wait on commands, otherwise the shutdown latency would
+				// get too large as a Cmd can run for some time (e.g. tick
+				// commands that sleep for half a second). A plain Cmd can't be
+				// cancelled; use a CmdCtx for commands that should stop when
+				// the program exits.
+				if cmd != nil {
+					p.schedule(PriorityNormal, cmd)
 				}
-
-				// Don't wait on these goroutines, otherwise the shutdown
-				// latency would get too large as a Cmd can run for some time
-				// (e.g. tick commands that sleep for half a second). It's not
-				// possible to cancel them so we'll have to leak the goroutine
-				// until Cmd returns.
-				go func() {
-					msg := cmd() // this can be long.
-					p.Send(msg)
-				}()
+				// The scheduler counts the command from now on, see send.
+				p.inflight.Done()
 			}
 		}
 	}()
@@ -203,9 +276,363 @@ func (p *Program) handleCommands(cmds chan Cmd) chan struct{} {
 	return ch
 }
 
+// schedule queues cmd to run with the given priority and delivers its result.
+// The command is in flight while it waits in the queue, see Shutdown.
+func (p *Program) schedule(priority Priority, cmd Cmd) {
+	p.inflight.Add(1)
+	p.scheduler.schedule(priority, func(sl *slot) {
+		defer p.inflight.Done()
+		if p.ctx.Err() != nil {
+			return
+		}
+		p.dispatch(sl, priority, p.runCmd(sl, cmd)) // this can be long.
+	})
+}
+
+// dispatch delivers msg, the result of a command run with the given priority
+// in sl. A ContextCmd is run within the same slot. Other messages go through
+// the event loop, so the filter and middleware see the BatchMsg and
+// PriorityMsg of commands too; the commands of a Batch keep the priority of
+// the command that returned it.
+func (p *Program) dispatch(sl *slot, priority Priority, msg Msg) {
+	switch msg := msg.(type) {
+	case contextCmdMsg:
+		p.dispatch(sl, priority, p.runContextCmd(sl, CmdCtx(msg)))
+	case BatchMsg:
+		if priority != PriorityNormal {
+			cmds := make(BatchMsg, len(msg))
+			for i, cmd := range msg {
+				cmds[i] = Prioritize(priority, cmd)
+			}
+			msg = cmds
+		}
+		p.deliver(msg)
+	default:
+		p.deliver(msg)
+	}
+}
+
+// call runs cmd through the scheduler and waits for its result, resolving a
+// ContextCmd within the same slot. Commands executed in order, such as those
+// in a Sequence, use this so that a command completes before the next one
+// starts. It returns nil if the program exits first.
+func (p *Program) call(priority Priority, cmd Cmd) Msg {
+	result := make(chan Msg, 1)
+	p.scheduler.schedule(priority, func(sl *slot) {
+		if p.ctx.Err() != nil {
+			result <- nil
+			return
+		}
+		msg := p.runCmd(sl, cmd)
+		if c, ok := msg.(PriorityMsg); ok && c.Cmd != nil {
+			msg = p.runCmd(sl, c.Cmd)
+		}
+		result <- msg
+	})
+	select {
+	case msg := <-result:
+		return msg
+	case <-p.ctx.Done():
+		return nil
+	}
+}
+
+// runSequence runs the commands of msg one at a time, in order, delivering
+// each result before the next command starts.
+func (p *Program) runSequence(msg sequenceMsg) {
+	p.exec(func() {
+		for _, cmd := range msg {
+			if cmd == nil {
+				continue
+			}
+			if p.ctx.Err() != nil {
+				return
+			}
+
+			msg := p.call(PriorityNormal, cmd)
+			if batchMsg, ok := msg.(BatchMsg); ok {
+				g, _ := errgroup.WithContext(p.ctx)
+				for _, cmd := range batchMsg {
+					cmd := cmd
+					g.Go(func() error {
+						p.deliver(p.call(PriorityNormal, cmd))
+						return nil
+					})
+				}
+
+				//nolint:errcheck,gosec
+				g.Wait() // wait for all commands from batch msg to finish
+				continue
+			}
+
+			p.deliver(msg)
+		}
+	})
+}
+
+// scheduler runs commands, at most limit at a time if limit is positive.
+// Commands over the limit wait in a queue ordered by priority, then by the
+// order in which they were scheduled.
+type scheduler struct {
+	mtx     sync.Mutex
+	limit   int
+	running int
+	queue   jobQueue
+	seq     uint64
+
+	// exec starts a job in its own goroutine.
+	exec func(func())
+}
+
+type job struct {
+	priority Priority
+	seq      uint64
+	run      func(*slot)
+
+	// resume is closed to hand a slot back to a running job that gave its
+	// own up, see acquire.
+	resume chan struct{}
+}
+
+// schedule runs fn now if a slot is free, or queues it otherwise.
+func (s *scheduler) schedule(priority Priority, fn func(*slot)) {
+	s.mtx.Lock()
+	if s.limit > 0 && s.running >= s.limit {
+		s.seq++
+		heap.Push(&s.queue, &job{priority: priority, seq: s.seq, run: fn})
+		s.mtx.Unlock()
+		return
+	}
+	s.running++
+	s.mtx.Unlock()
+	s.start(priority, fn)
+}
+
+// start runs fn in a new slot, then hands the slot over to the next queued
+// job.
+func (s *scheduler) start(priority Priority, fn func(*slot)) {
+	sl := &slot{s: s, priority: priority}
+	s.exec(func() {
+		defer sl.finish()
+		fn(sl)
+	})
+}
+
+// release hands a slot over to the next queued job, or frees it.
+func (s *scheduler) release() {
+	s.mtx.Lock()
+	if s.queue.Len() == 0 {
+		s.running--
+		s.mtx.Unlock()
+		return
+	}
+	next := heap.Pop(&s.queue).(*job)
+	s.mtx.Unlock()
+	if next.resume != nil {
+		close(next.resume)
+		return
+	}
+	s.start(next.priority, next.run)
+}
+
+// acquire waits for a slot for a running job that gave its own up. The job
+// goes ahead of the queued jobs of the same priority, which have not started.
+func (s *scheduler) acquire(priority Priority) {
+	s.mtx.Lock()
+	if s.limit <= 0 || s.running < s.limit {
+		s.running++
+		s.mtx.Unlock()
+		return
+	}
+	j := &job{priority: priority, resume: make(chan struct{})}
+	heap.Push(&s.queue, j)
+	s.mtx.Unlock()
+	<-j.resume
+}
+
+// slot is the place of a running job within the scheduler's limit. The job
+// gives it up while it waits on the clock, see sleep, so that commands such as
+// TickContext do not keep other commands from running.
+type slot struct {
+	mtx      sync.Mutex
+	s        *scheduler
+	priority Priority
+
+	// waiting counts the goroutines of the job that wait on the clock, and
+	// done is set once the job has returned.
+	waiting int
+	done    bool
+}
+
+type slotKey struct{}
+
+// slotFromContext returns the slot of the command that ctx was passed to, or
+// nil.
+func slotFromContext(ctx context.Context) *slot {
+	sl, _ := ctx.Value(slotKey{}).(*slot)
+	return sl
+}
+
+// release gives the slot up while a goroutine of the job waits.
+func (sl *slot) release() {
+	if sl == nil {
+		return
+	}
+	sl.mtx.Lock()
+	defer sl.mtx.Unlock()
+	sl.waiting++
+	if sl.waiting == 1 && !sl.done {
+		sl.s.release()
+	}
+}
+
+// acquire takes the slot back once no goroutine of the job waits anymore. The
+// lock is held while it waits, so that the slot is not given up again before
+// it is back.
+func (sl *slot) acquire() {
+	if sl == nil {
+		return
+	}
+	sl.mtx.Lock()
+	defer sl.mtx.Unlock()
+	sl.waiting--
+	if sl.waiting == 0 && !sl.done {
+		sl.s.acquire(sl.priority)
+	}
+}
+
+// finish gives the slot up once the job has returned, unless it already has.
+func (sl *slot) finish() {
+	sl.mtx.Lock()
+	defer sl.mtx.Unlock()
+	sl.done = true
+	if sl.waiting == 0 {
+		sl.s.release()
+	}
+}
+
+// jobQueue is a heap of jobs, highest priority first.
+type jobQueue []*job
+
+func (q jobQueue) Len() int { return len(q) }
+func (q jobQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q jobQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *jobQueue) Push(x interface{}) { *q = append(*q, x.(*job)) }
+func (q *jobQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	j := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return j
+}
+
+// exec runs fn in a goroutine that is tracked as an in-flight command.
+func (p *Program) exec(fn func()) {
+	p.inflight.Add(1)
+	go func() {
+		defer p.inflight.Done()
+		fn()
+	}()
+}
+
+// runContextCmd runs cmd in sl with a child of the program's context. The
+// context is cancelled once cmd returns, or earlier when the program shuts
+// down.
+func (p *Program) runContextCmd(sl *slot, cmd CmdCtx) Msg {
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+	if sl != nil {
+		ctx = context.WithValue(ctx, slotKey{}, sl)
+	}
+	return cmd(ctx)
+}
+
+// resolve runs the CmdCtx carried by msg, if any, and returns its result.
+// Commands are resolved within their scheduler slot, so that a context-aware
+// command completes before the next one of a Sequence starts, and is traced
+// as part of the command that returned it.
+func (p *Program) resolve(sl *slot, msg Msg) Msg {
+	if cmd, ok := msg.(contextCmdMsg); ok {
+		return p.runContextCmd(sl, CmdCtx(cmd))
+	}
+	return msg
+}
+
+// waitForCommands blocks until all in-flight commands have returned or the
+// timeout elapses. It reports whether all commands finished in time.
+func (p *Program) waitForCommands(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		p.inflight.Wait()
+		close(done)
+	}()
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
 // eventLoop is the central message loop. It receives and handles the default
 // Bubble Tea messages, update the model and triggers redraws.
 func (p *Program) eventLoop(model Model, cmds chan Cmd) (Model, error) {
+	// The innermost update handles quitting, window titles and the commands
+	// of Batch, Prioritize and Sequence before delegating to the model, which
+	// never sees a BatchMsg or PriorityMsg. Everything else is wrapped around it, so the filter and
+	// middleware see these messages too. updated is set when the model's
+	// Update is called, which is when the model may need saving.
+	var quit, updated bool
+	update := p.chain(func(model Model, msg Msg) (Model, Cmd) {
+		switch msg := msg.(type) {
+		case QuitMsg:
+			quit = true
+			return model, nil
+
+		case setWindowTitleMsg:
+			SetTitle(string(msg))
+
+		case BatchMsg:
+			for _, cmd := range msg {
+				p.inflight.Add(1)
+				cmds <- cmd
+			}
+			return model, nil
+
+		case PriorityMsg:
+			if msg.Cmd != nil {
+				p.schedule(msg.Priority, msg.Cmd)
+			}
+			return model, nil
+
+		case sequenceMsg:
+			p.runSequence(msg)
+		}
+		updated = true
+		return model.Update(msg)
+	})
+
+	// With frame coalescing, a render is scheduled for the next animation
+	// frame after an update, and further updates before then share it.
+	// Without animation frames, the model is rendered after every update.
+	frames := p.hasFrames()
+	coalesce := frames && !p.startupOptions.has(withoutFrameCoalescing)
+	var frameScheduled bool
+
+	// While the program drains, see Shutdown, the messages of in-flight
+	// commands are still handled but no new work is started.
+	var draining bool
+
 	for {
 		select {
 		case <-p.ctx.Done():
@@ -214,86 +641,147 @@ func (p *Program) eventLoop(model Model, cmds chan Cmd) (Model, error) {
 		case err := <-p.errs:
 			return model, err
 
+		case <-p.frames:
+			frameScheduled = false
+			p.renderer.render(model, p.Send)
+
 		case msg := <-p.msgs:
-			// Filter messages.
-			if p.filter != nil {
-				msg = p.filter(model, msg)
-			}
 			if msg == nil {
 				continue
 			}
 
 			// Handle special internal messages.
-			switch msg := msg.(type) {
-			case QuitMsg:
+			switch msg.(type) {
+			case drainMsg:
+				draining = true
+				p.stopSubscriptions()
+				continue
+
+			case shutdownMsg:
 				return model, nil
 
-			case BatchMsg:
-				for _, cmd := range msg {
-					cmds <- cmd
+			case BatchMsg, contextCmdMsg, PriorityMsg, sequenceMsg:
+				if draining {
+					continue
 				}
+			}
+
+			switch msg := msg.(type) {
+			case contextCmdMsg:
+				p.schedule(PriorityNormal, func() Msg { return msg })
 				continue
 
-			case sequenceMsg:
-				go func() {
-					// Execute commands one at a time, in order.
-					for _, cmd := range msg {
-						if cmd == nil {
-							continue
-						}
-
-						msg := cmd()
-						if batchMsg, ok := msg.(BatchMsg); ok {
-							g, _ := errgroup.WithContext(p.ctx)
-							for _, cmd := range batchMsg {
-								cmd := cmd
-								g.Go(func() error {
-									p.Send(cmd())
-									return nil
-								})
-							}
-
-							//nolint:errcheck,gosec
-							g.Wait() // wait for all commands from batch msg to finish
-							continue
-						}
-
-						p.Send(msg)
-					}
-				}()
+			case persistMsg:
+				if draining {
+					// Save once the program exits.
+					p.persistence.scheduled = true
+					continue
+				}
+				p.persist(model, false)
+				continue
 
-			case setWindowTitleMsg:
-				SetTitle(string(msg))
+			case snapshotMsg:
+				msg.reply <- takeSnapshot(model)
+				continue
+
+			case emitJSMsg:
+				if p.bridge != nil {
+					p.bridge.emit(msg)
+				}
+				continue
+
+			case debugRenderMsg:
+				if p.debugger != nil {
+					p.debugger.renderSelected()
+				}
+				continue
 			}
 
 			var cmd Cmd
-			model, cmd = model.Update(msg)   // run update
-			p.renderer.render(model, p.Send) // send view to renderer first
+			updated = false
+			model, cmd = p.traceUpdate(update, model, msg) // run update through middleware
+			if quit {
+				return model, nil
+			}
+			persist := p.persistence != nil && updated
+			if draining {
+				// Save once the program exits, and drop the command.
+				if persist {
+					p.persistence.scheduled = true
+				}
+				cmd = nil
+			} else {
+				p.syncSubscriptions(model) // start or stop subscriptions
+				if persist {
+					p.schedulePersist()
+				}
+			}
+
+			// Send view to renderer first.
+			switch {
+			case !coalesce:
+				p.renderer.render(model, p.Send)
+			case !frameScheduled:
+				frameScheduled = true
+				requestAnimationFrame(p, func(float64, func(Msg)) {
+					// frames is buffered and only one frame is scheduled at
+					// a time, so this never blocks, even when called from
+					// within the event loop.
+					select {
+					case p.frames <- struct{}{}:
+					default:
+					}
+				}, p.Send)
+			}
 
-			// Schedule command to run after next frame render for better INP
+			// Schedule command to run after next frame render for better INP.
+			// It is in flight from now on, so that Shutdown waits for it.
 			if cmd != nil {
-				requestAnimationFrame(func(float64, func(Msg)) {
-					cmds <- cmd // run command after UI updates
+				p.inflight.Add(1)
+			}
+			switch {
+			case cmd == nil:
+			case !frames:
+				cmds <- cmd
+			default:
+				requestAnimationFrame(p, func(float64, func(Msg)) {
+					select {
+					case cmds <- cmd: // run command after UI updates
+					case <-p.ctx.Done():
+						p.inflight.Done()
+					}
 				}, p.Send)
 			}
 		}
 	}
 }
 
+// hasFrames reports whether the program renders in animation frames. Without
+// a renderer, or natively without a window set by UseGostDOM or a test, there
+// are no frames to wait for.
+func (p *Program) hasFrames() bool {
+	r := p.renderer
+	if d, ok := r.(*debugRenderer); ok {
+		r = d.d.next
+	}
+	if _, ok := r.(*nilRenderer); ok {
+		return false
+	}
+	return global() != nil
+}
+
 // Run initializes the program and runs its event loops, blocking until it gets
 // terminated by either [Program.Quit], [Program.Kill], or its signal handler.
 // Returns the final model.
 func (p *Program) Run() (Model, error) {
-	// Set the current program for panic handling in callbacks
-	currentProgram = p
-	defer func() {
-		currentProgram = nil
-	}()
+	atomic.StoreUint32(&p.started, 1)
+	defer close(p.stopped)
 
 	handlers := handlers{}
 	cmds := make(chan Cmd)
 	p.errs = make(chan error)
 	p.finished = make(chan struct{}, 1)
+	p.frames = make(chan struct{}, 1)
 
 	defer p.cancel()
 
@@ -310,25 +798,44 @@ func (p *Program) Run() (Model, error) {
 
 	// If no renderer is set use the standard one.
 	if p.renderer == nil {
-		p.renderer = newRenderer()
+		p.renderer = newRenderer(p)
 	}
 
-	// Initialize the program.
+	// Initialize the program, restoring its persisted state first.
 	model := p.initialModel
+	var restoreErr error
+	if p.persistence != nil {
+		model, restoreErr = p.persistence.restore(model)
+	}
+	// A snapshot taken before a hot reload is newer than the persisted state.
+	model, snapshotErr := restoreSnapshot(model, loadSnapshot())
+	if snapshotErr != nil {
+		warnRestore(snapshotErr)
+	}
 	if initCmd := model.Init(); initCmd != nil {
 		ch := make(chan struct{})
 		handlers.add(ch)
 
+		p.inflight.Add(1)
 		go func() {
 			defer close(ch)
 
 			select {
 			case cmds <- initCmd:
 			case <-p.ctx.Done():
+				p.inflight.Done()
 			}
 		}()
 	}
 
+	// Record the initial state for the debugger.
+	if p.debugger != nil {
+		p.renderer = p.debugger.attach(model, p.renderer)
+	}
+
+	// Start the model's subscriptions.
+	p.syncSubscriptions(model)
+
 	// Start the renderer.
 	p.renderer.start()
 
@@ -338,6 +845,22 @@ func (p *Program) Run() (Model, error) {
 	// Process commands.
 	handlers.add(p.handleCommands(cmds))
 
+	if restoreErr != nil {
+		p.exec(func() { p.deliver(PersistErrorMsg{Err: restoreErr}) })
+	}
+
+	// Let masc serve snapshot the model before a hot reload.
+	defer p.serveSnapshots()()
+
+	// Let JavaScript send messages to the program.
+	if p.bridge != nil {
+		p.bridge.install(p.ctx, p.Send)
+		defer p.bridge.uninstall()
+	}
+
+	// Shut down when the page is unloaded.
+	defer p.shutdownOnUnload()()
+
 	// Run event loop, handle updates and draw.
 	model, err := p.eventLoop(model, cmds)
 	killed := p.ctx.Err() != nil
@@ -348,12 +871,25 @@ func (p *Program) Run() (Model, error) {
 		p.renderer.render(model, p.Send)
 	}
 
+	// Save changes that are still waiting for a throttled save.
+	if p.persistence != nil && (p.persistence.scheduled || p.persistence.pending()) {
+		p.persist(model, true)
+	}
+
+	p.runShutdownHooks(model)
+	close(p.exited)
+
 	// Tear down.
 	p.cancel()
 
 	// Wait for all handlers to finish.
 	handlers.shutdown()
 
+	// Give in-flight commands a chance to observe the cancellation and return.
+	if p.commandsTimeout > 0 {
+		p.waitForCommands(p.commandsTimeout)
+	}
+
 	// Restore terminal state.
 	p.shutdown()
 
@@ -384,9 +920,13 @@ func (p *Program) Start() error {
 // purposes.
 //
 // If the program hasn't started yet this will be a blocking operation.
-// If the program has already been terminated this will be a no-op, so it's safe
-// to send messages after the program has exited.
+// If the program has already been terminated or is shutting down, see
+// [Program.Shutdown], this will be a no-op, so it's safe to send messages
+// after the program has exited.
 func (p *Program) Send(msg Msg) {
+	if p.shuttingDown() {
+		return
+	}
 	select {
 	case <-p.ctx.Done():
 	case p.msgs <- msg:
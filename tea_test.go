@@ -2,6 +2,8 @@ package masc
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -39,6 +41,10 @@ func (m *testModel) Render(send func(Msg)) ComponentOrHTML {
 	go func() {
 		time.Sleep(20 * time.Millisecond)
 		for {
+			if m.testSuite.isDone {
+				// The test has finished; don't leak renders into later tests.
+				return
+			}
 			if len(m.testSuite.callbacks) == 0 {
 				time.Sleep(200 * time.Millisecond)
 			} else {
@@ -96,6 +102,7 @@ func testTeaWithFilter(t *testing.T, preventCount uint32) {
 	m.testSuite = ts
 	shutdowns := uint32(0)
 	p := NewProgram(m,
+		WithoutFrameCoalescing(),
 		WithFilter(func(_ Model, msg Msg) Msg {
 			if _, ok := msg.(QuitMsg); !ok {
 				return msg
@@ -193,7 +200,7 @@ func TestTeaBatchMsg(t *testing.T) {
 
 	m := &testModel{}
 	m.testSuite = ts
-	p := NewProgram(m)
+	p := NewProgram(m, WithoutFrameCoalescing())
 	go func() {
 		p.Send(BatchMsg{inc, inc})
 
@@ -231,7 +238,7 @@ func TestTeaSequenceMsg(t *testing.T) {
 
 	m := &testModel{}
 	m.testSuite = ts
-	p := NewProgram(m)
+	p := NewProgram(m, WithoutFrameCoalescing())
 	go p.Send(sequenceMsg{inc, inc, Quit})
 
 	if _, err := p.Run(); err != nil {
@@ -261,7 +268,7 @@ func TestTeaSequenceMsgWithBatchMsg(t *testing.T) {
 
 	m := &testModel{}
 	m.testSuite = ts
-	p := NewProgram(m)
+	p := NewProgram(m, WithoutFrameCoalescing())
 	go p.Send(sequenceMsg{batch, inc, Quit})
 
 	if _, err := p.Run(); err != nil {
@@ -273,6 +280,81 @@ func TestTeaSequenceMsgWithBatchMsg(t *testing.T) {
 	}
 }
 
+// updateFuncModel calls update with the messages it receives.
+type updateFuncModel struct {
+	Core
+	update func(Msg) Cmd
+}
+
+func (m *updateFuncModel) Init() Cmd { return nil }
+
+func (m *updateFuncModel) Update(msg Msg) (Model, Cmd) {
+	return m, m.update(msg)
+}
+
+func (m *updateFuncModel) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+// batchFromUpdateMsg makes the model of TestTeaFilterSeesCommandMsgs return a
+// Batch from Update.
+type batchFromUpdateMsg struct{}
+
+func TestTeaFilterSeesCommandMsgs(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	var (
+		mtx              sync.Mutex
+		filtered, update []string
+	)
+	record := func(into *[]string, msg Msg) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		*into = append(*into, fmt.Sprintf("%T", msg))
+	}
+	inc := func() Msg { return incrementMsg{} }
+	incremented := make(chan struct{}, 3)
+	m := &updateFuncModel{update: func(msg Msg) Cmd {
+		record(&update, msg)
+		switch msg.(type) {
+		case incrementMsg:
+			incremented <- struct{}{}
+		case batchFromUpdateMsg:
+			return Batch(Prioritize(PriorityHigh, inc))
+		}
+		return nil
+	}}
+	p := NewProgram(m,
+		WithoutRenderer(),
+		WithFilter(func(_ Model, msg Msg) Msg {
+			record(&filtered, msg)
+			return msg
+		}),
+	)
+	go func() {
+		p.Send(BatchMsg{inc})
+		<-incremented
+		p.Send(batchFromUpdateMsg{})
+		<-incremented
+		p.Send(sequenceMsg{inc, Quit})
+	}()
+
+	if _, err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	want := "[masc.BatchMsg masc.incrementMsg masc.batchFromUpdateMsg masc.BatchMsg masc.PriorityMsg masc.incrementMsg masc.sequenceMsg masc.incrementMsg masc.QuitMsg]"
+	if got := fmt.Sprint(filtered); got != want {
+		t.Errorf("filter got %s, want %s", got, want)
+	}
+	// Update does not receive the BatchMsg, the PriorityMsg, nor the QuitMsg.
+	want = "[masc.incrementMsg masc.batchFromUpdateMsg masc.incrementMsg masc.sequenceMsg masc.incrementMsg]"
+	if got := fmt.Sprint(update); got != want {
+		t.Errorf("update got %s, want %s", got, want)
+	}
+}
+
 func TestTeaSend(t *testing.T) {
 	ts := testSuite(t)
 	defer ts.done()
@@ -301,3 +383,384 @@ func TestTeaNoRun(t *testing.T) {
 	m := &testModel{}
 	NewProgram(m)
 }
+
+type initCmdModel struct {
+	Core
+	init Cmd
+}
+
+func (m *initCmdModel) Init() Cmd                             { return m.init }
+func (m *initCmdModel) Update(Msg) (Model, Cmd)               { return m, nil }
+func (m *initCmdModel) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+func TestTeaContextCmd(t *testing.T) {
+	for _, stop := range []string{"quit", "kill"} {
+		t.Run(stop, func(t *testing.T) {
+			ts := testSuite(t)
+			defer ts.done()
+
+			started := make(chan struct{})
+			var cancelled atomic.Value
+			m := &initCmdModel{init: ContextCmd(func(ctx context.Context) Msg {
+				close(started)
+				<-ctx.Done()
+				cancelled.Store(true)
+				return nil
+			})}
+			p := NewProgram(m, WithoutRenderer(), WithCommandsTimeout(time.Second))
+			go func() {
+				<-started
+				if stop == "quit" {
+					p.Quit()
+				} else {
+					p.Kill()
+				}
+			}()
+
+			_, err := p.Run()
+			if stop == "quit" && err != nil {
+				t.Fatal(err)
+			}
+			if stop == "kill" && err != ErrProgramKilled {
+				t.Fatalf("Expected %v, got %v", ErrProgramKilled, err)
+			}
+			if cancelled.Load() == nil {
+				t.Fatal("expected command context to be cancelled before Run returned")
+			}
+		})
+	}
+}
+
+func TestTeaSequenceContextCmd(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	var order []string
+	step := func(name string) Cmd {
+		return ContextCmd(func(ctx context.Context) Msg {
+			if ctx.Err() != nil {
+				t.Errorf("context for %s cancelled early", name)
+			}
+			time.Sleep(time.Millisecond)
+			order = append(order, name)
+			return nil
+		})
+	}
+	m := &initCmdModel{init: Sequence(step("a"), step("b"), Quit)}
+	p := NewProgram(m, WithoutRenderer())
+	if _, err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(order); got != "[a b]" {
+		t.Fatalf("expected commands to run in order, got %s", got)
+	}
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	var wg sync.WaitGroup
+	s := &scheduler{limit: 1, exec: func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}}
+
+	release := make(chan struct{})
+	s.schedule(PriorityNormal, func(*slot) { <-release })
+
+	var mtx sync.Mutex
+	var order []string
+	job := func(name string) func(*slot) {
+		return func(*slot) {
+			mtx.Lock()
+			order = append(order, name)
+			mtx.Unlock()
+		}
+	}
+	s.schedule(PriorityLow, job("low"))
+	s.schedule(PriorityNormal, job("normal 1"))
+	s.schedule(PriorityHigh, job("high"))
+	s.schedule(PriorityNormal, job("normal 2"))
+	close(release)
+	wg.Wait()
+
+	if got := fmt.Sprint(order); got != "[high normal 1 normal 2 low]" {
+		t.Fatalf("unexpected run order %s", got)
+	}
+}
+
+type concurrencyModel struct {
+	Core
+	cmds     Cmd
+	received int
+	done     chan struct{}
+}
+
+func (m *concurrencyModel) Init() Cmd { return m.cmds }
+func (m *concurrencyModel) Update(msg Msg) (Model, Cmd) {
+	if _, ok := msg.(incrementMsg); ok {
+		m.received++
+		if m.received == 10 {
+			close(m.done)
+		}
+	}
+	return m, nil
+}
+func (m *concurrencyModel) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+func TestTeaCommandConcurrency(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	var running, max int32
+	work := func() Msg {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return incrementMsg{}
+	}
+	var cmds []Cmd
+	for i := 0; i < 10; i++ {
+		if i%2 == 0 {
+			cmds = append(cmds, work)
+		} else {
+			cmds = append(cmds, ContextCmd(func(context.Context) Msg { return work() }))
+		}
+	}
+
+	m := &concurrencyModel{cmds: Batch(cmds...), done: make(chan struct{})}
+	p := NewProgram(m, WithoutRenderer(), WithCommandConcurrency(3))
+	go func() {
+		<-m.done
+		p.Quit()
+	}()
+	if _, err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if m.received != 10 {
+		t.Fatalf("expected 10 messages, got %d", m.received)
+	}
+	if max > 3 {
+		t.Fatalf("expected at most 3 concurrent commands, got %d", max)
+	}
+}
+
+// TestTeaConcurrencyClockWait tests that commands waiting on the clock do not
+// keep other commands from running.
+func TestTeaConcurrencyClockWait(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	const n = 3
+	var cmds []Cmd
+	for i := 0; i < n; i++ {
+		cmds = append(cmds, TickContext(time.Hour, func(time.Time) Msg { return incrementMsg{} }))
+	}
+	ran := make(chan struct{})
+	cmds = append(cmds, func() Msg {
+		close(ran)
+		return nil
+	})
+
+	m := &initCmdModel{init: Batch(cmds...)}
+	p := NewProgram(m, WithoutRenderer(), WithClock(newFakeClock()), WithCommandConcurrency(n))
+	go func() {
+		select {
+		case <-ran:
+		case <-time.After(5 * time.Second):
+			t.Error("expected a command to run while the ticks wait")
+		}
+		p.Quit()
+	}()
+	if _, err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// countdownModel counts down from n, with a command per step, and quits at 0.
+type countdownModel struct {
+	Core
+	n int
+}
+
+func (m *countdownModel) Init() Cmd { return m.step }
+
+func (m *countdownModel) Update(msg Msg) (Model, Cmd) {
+	if _, ok := msg.(incrementMsg); !ok {
+		return m, nil
+	}
+	m.n--
+	if m.n <= 0 {
+		return m, Quit
+	}
+	return m, m.step
+}
+
+func (m *countdownModel) step() Msg { return incrementMsg{} }
+
+func (m *countdownModel) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+// TestTeaWithoutRenderer tests that a program without a renderer runs without
+// a window, and so without animation frames.
+func TestTeaWithoutRenderer(t *testing.T) {
+	defer func(g jsObject) { globalValue = g }(globalValue)
+	globalValue = nil
+
+	p := NewProgram(&countdownModel{n: 3}, WithoutRenderer())
+	m, err := p.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := m.(*countdownModel).n; n != 0 {
+		t.Fatalf("expected the countdown to finish, got %d", n)
+	}
+}
+
+func TestTeaFrameCoalescing(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	rec := &recordingRenderer{}
+	p := NewProgram(&counterModel{}, func(p *Program) { p.renderer = rec })
+	errs := make(chan error, 1)
+	go func() {
+		_, err := p.Run()
+		errs <- err
+	}()
+
+	for i := 0; i < 3; i++ {
+		p.Send(incrementMsg{})
+	}
+	if rec.count() != 1 {
+		t.Fatalf("expected only the initial render before the frame, got %d renders", rec.count())
+	}
+
+	ts.invokeCallbackRequestAnimationFrame(0)
+	waitFor(t, func() bool { return rec.count() == 2 })
+	if n := rec.last().(*counterModel).n; n != 3 {
+		t.Fatalf("expected the frame to render all 3 updates, got %d", n)
+	}
+
+	p.Quit()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestProgramsRenderIsolation tests that programs rendering into different
+// nodes each batch their own rerenders.
+func TestProgramsRenderIsolation(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	var frames []*jsFuncImpl
+	orig := funcOfImpl
+	funcOfImpl = func(fn func(this jsObject, args []jsObject) interface{}) jsFunc {
+		f := orig(fn).(*jsFuncImpl)
+		frames = append(frames, f)
+		return f
+	}
+	defer func() { funcOfImpl = orig }()
+	runFrames := func() {
+		pending := frames
+		frames = nil
+		for _, f := range pending {
+			f.goFunc(undefined(), []jsObject{valueOf(0.0)})
+		}
+	}
+
+	var renders [2]int
+	renderers := make([]*standardRenderer, 2)
+	comps := make([]*componentFunc, 2)
+	for i := range renderers {
+		i := i
+		root := &objectRecorder{ts: ts, name: fmt.Sprintf("root%d", i+1)}
+		ts.truthies.mock(root.name, true) // checked by the renderer
+		ts.truthies.mock(root.name, true) // and by renderIntoNode
+		ts.strings.mock(root.name+`.Get("nodeName")`, "DIV")
+		ts.strings.mock(`global.Get("document").Get("readyState")`, "complete")
+		p := NewProgram(&testModel{})
+		renderers[i] = newNodeRenderer(p, root).(*standardRenderer)
+		comps[i] = &componentFunc{
+			render: func() ComponentOrHTML {
+				renders[i]++
+				return Tag("div", Text(fmt.Sprint(renders[i])))
+			},
+			skipRender: func(Component) bool { return false },
+		}
+		renderers[i].render(comps[i], send)
+	}
+	if renderers[0].batch == renderers[1].batch {
+		t.Fatal("expected each renderer to have its own batch")
+	}
+	runFrames()
+
+	ts.record("(rerender the first program)")
+	renderers[0].render(comps[0], send)
+	if len(renderers[1].batch.batch) != 0 || renderers[1].batch.scheduled {
+		t.Fatal("expected the second program's batch to be left alone")
+	}
+	runFrames()
+	if renders != [2]int{2, 1} {
+		t.Fatalf("expected only the first program to rerender, got %v renders", renders)
+	}
+}
+
+type panicModel struct {
+	testModel
+}
+
+func (m *panicModel) Update(msg Msg) (Model, Cmd) {
+	if _, ok := msg.(incrementMsg); ok {
+		panic("boom")
+	}
+	return m, nil
+}
+
+// TestProgramsPanicIsolation tests that a panic in one program is handled by
+// its own panic handler and leaves other programs running.
+func TestProgramsPanicIsolation(t *testing.T) {
+	var panics [2][]interface{}
+	handler := func(i int) ProgramOption {
+		return WithPanicHandler(func(r interface{}) { panics[i] = append(panics[i], r) })
+	}
+	p1 := NewProgram(&panicModel{}, WithoutRenderer(), WithoutFrameCoalescing(), handler(0))
+	m2 := &persistedModel{}
+	p2 := NewProgram(m2, WithoutRenderer(), WithoutFrameCoalescing(), handler(1))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p1.Run() //nolint:errcheck
+	}()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := p2.Run()
+		errs <- err
+	}()
+
+	p1.Send(incrementMsg{})
+	<-done
+	p2.Send(incrementMsg{})
+	p2.Quit()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(panics[0]) != 1 || panics[0][0] != "boom" {
+		t.Fatalf("expected the first program's handler to get the panic, got %v", panics[0])
+	}
+	if len(panics[1]) != 0 {
+		t.Fatalf("expected no panics in the second program, got %v", panics[1])
+	}
+	if m2.N != 1 {
+		t.Fatalf("expected the second program to keep running, got N=%d", m2.N)
+	}
+}
@@ -12,11 +12,6 @@ import (
 	"testing"
 )
 
-var _ = func() bool {
-	isTest = true
-	return true
-}()
-
 var mutex sync.Mutex
 
 // recoverStr runs f and returns the recovered panic as a string.
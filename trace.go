@@ -0,0 +1,151 @@
+package masc
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Tracer receives timing events from a Program, see WithTracer. Commands run
+// on their own goroutines, so implementations must be safe for concurrent use.
+type Tracer interface {
+	// MsgReceived is called when the event loop takes msg from its queue to
+	// hand it to Update.
+	MsgReceived(msg Msg)
+
+	// Update is called after Update handled msg, which took d, including the
+	// program's middleware.
+	Update(msg Msg, d time.Duration)
+
+	// Render is called after component c was rendered. render is the time
+	// spent in its Render method, and reconcile the time spent reconciling
+	// the result with the DOM, which includes rendering its children.
+	Render(c Component, render, reconcile time.Duration)
+
+	// CmdStart is called when a command starts running. id identifies the
+	// command in the matching call to CmdFinish.
+	CmdStart(id uint64)
+
+	// CmdFinish is called when the command identified by id returned msg,
+	// which took d.
+	CmdFinish(id uint64, msg Msg, d time.Duration)
+
+	// FrameOverrun is called when rendering a frame used up its budget after
+	// elapsed, and the deferred remaining components are left for the next
+	// frame.
+	FrameOverrun(elapsed time.Duration, deferred int)
+}
+
+// runCmd runs cmd in sl, and the CmdCtx it returns if any, reporting them to
+// the program's tracer as one command.
+func (p *Program) runCmd(sl *slot, cmd Cmd) Msg {
+	if p.tracer == nil {
+		return p.resolve(sl, cmd())
+	}
+	id := atomic.AddUint64(&p.cmdSeq, 1)
+	p.tracer.CmdStart(id)
+	start := p.traceClock.Now()
+	msg := p.resolve(sl, cmd())
+	p.tracer.CmdFinish(id, msg, p.traceClock.Now().Sub(start))
+	return msg
+}
+
+// traceUpdate calls update with msg, reporting it to the program's tracer.
+func (p *Program) traceUpdate(update UpdateFunc, model Model, msg Msg) (Model, Cmd) {
+	if p.tracer == nil {
+		return update(model, msg)
+	}
+	p.tracer.MsgReceived(msg)
+	start := p.traceClock.Now()
+	model, cmd := update(model, msg)
+	p.tracer.Update(msg, p.traceClock.Now().Sub(start))
+	return model, cmd
+}
+
+// tracer returns the tracer of the batch's program, if any.
+func (b *batchRenderer) tracer() Tracer {
+	if b == nil || b.program == nil {
+		return nil
+	}
+	return b.program.tracer
+}
+
+// now returns the time of the batch's program if it has a tracer, and the
+// zero time otherwise.
+func (b *batchRenderer) now() time.Time {
+	if b.tracer() == nil {
+		return time.Time{}
+	}
+	return b.program.traceClock.Now()
+}
+
+// traceRender reports the render of c, which started at start and whose
+// Render method returned at rendered, to the tracer of the batch's program.
+func (b *batchRenderer) traceRender(c Component, start, rendered time.Time) {
+	if t := b.tracer(); t != nil {
+		t.Render(c, rendered.Sub(start), b.now().Sub(rendered))
+	}
+}
+
+// traceOverrun reports a frame that used up its budget after elapsed
+// milliseconds to the tracer of the batch's program.
+func (b *batchRenderer) traceOverrun(elapsed float64, deferred int) {
+	if t := b.tracer(); t != nil {
+		t.FrameOverrun(time.Duration(elapsed*float64(time.Millisecond)), deferred)
+	}
+}
+
+// PerformanceTracer is a Tracer that records a program's events with the
+// browser's Performance API, so that they show up in the performance panel of
+// the devtools. Updates, renders, reconciliations and commands are recorded
+// as measures named after the type of their message or component, received
+// messages and frame overruns as marks.
+//
+//	p := masc.NewProgram(model, masc.WithTracer(masc.PerformanceTracer{}))
+type PerformanceTracer struct{}
+
+// MsgReceived implements Tracer.
+func (PerformanceTracer) MsgReceived(msg Msg) {
+	performance().Call("mark", fmt.Sprintf("masc:msg %T", msg))
+}
+
+// Update implements Tracer.
+func (PerformanceTracer) Update(msg Msg, d time.Duration) {
+	measure(fmt.Sprintf("masc:update %T", msg), 0, d)
+}
+
+// Render implements Tracer.
+func (PerformanceTracer) Render(c Component, render, reconcile time.Duration) {
+	measure(fmt.Sprintf("masc:render %T", c), reconcile, render)
+	measure(fmt.Sprintf("masc:reconcile %T", c), 0, reconcile)
+}
+
+// CmdStart implements Tracer. Commands are measured once they finish.
+func (PerformanceTracer) CmdStart(uint64) {}
+
+// CmdFinish implements Tracer.
+func (PerformanceTracer) CmdFinish(id uint64, msg Msg, d time.Duration) {
+	measure(fmt.Sprintf("masc:cmd %T", msg), 0, d)
+}
+
+// FrameOverrun implements Tracer.
+func (PerformanceTracer) FrameOverrun(elapsed time.Duration, deferred int) {
+	performance().Call("mark", fmt.Sprintf("masc:frame overrun (%d deferred)", deferred))
+}
+
+func performance() jsObject {
+	return global().Get("performance")
+}
+
+// measure records a measure called name that took d and ended before ago.
+func measure(name string, ago, d time.Duration) {
+	end := performance().Call("now").Float() - milliseconds(ago)
+	performance().Call("measure", name, map[string]interface{}{
+		"start": end - milliseconds(d),
+		"end":   end,
+	})
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,179 @@
+package masc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stepClock is a Clock whose time advances by a millisecond on every call to
+// Now.
+type stepClock struct {
+	mtx sync.Mutex
+	now time.Time
+}
+
+func (c *stepClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = c.now.Add(time.Millisecond)
+	return c.now
+}
+
+func (c *stepClock) NewTimer(d time.Duration) Timer { return systemClock{}.NewTimer(d) }
+
+type recordingTracer struct {
+	mtx    sync.Mutex
+	events []string
+}
+
+func (r *recordingTracer) record(format string, args ...interface{}) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTracer) MsgReceived(msg Msg) { r.record("msg %T", msg) }
+func (r *recordingTracer) Update(msg Msg, d time.Duration) {
+	r.record("update %T %v", msg, d)
+}
+func (r *recordingTracer) Render(c Component, render, reconcile time.Duration) {
+	r.record("render %T %v %v", c, render, reconcile)
+}
+func (r *recordingTracer) CmdStart(id uint64) { r.record("cmd %d", id) }
+func (r *recordingTracer) CmdFinish(id uint64, msg Msg, d time.Duration) {
+	r.record("cmd %d %T %v", id, msg, d)
+}
+func (r *recordingTracer) FrameOverrun(elapsed time.Duration, deferred int) {
+	r.record("overrun %v %d", elapsed, deferred)
+}
+
+type tracedModel struct {
+	Core
+	updated chan struct{}
+}
+
+func (m *tracedModel) Init() Cmd { return func() Msg { return incrementMsg{} } }
+
+func (m *tracedModel) Update(msg Msg) (Model, Cmd) {
+	if _, ok := msg.(incrementMsg); ok {
+		close(m.updated)
+	}
+	return m, nil
+}
+
+func (m *tracedModel) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	m := &tracedModel{updated: make(chan struct{})}
+	p := NewProgram(m,
+		WithoutRenderer(),
+		WithoutFrameCoalescing(),
+		WithTracer(tracer),
+	)
+	p.traceClock = &stepClock{}
+	go func() {
+		<-m.updated
+		p.Quit()
+	}()
+	if _, err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"cmd 1",
+		"cmd 1 masc.incrementMsg 1ms",
+		"msg masc.incrementMsg",
+		"update masc.incrementMsg 1ms",
+		"msg masc.QuitMsg",
+		"update masc.QuitMsg 1ms",
+	}
+	if !reflect.DeepEqual(tracer.events, want) {
+		t.Fatalf("expected events %q, got %q", want, tracer.events)
+	}
+}
+
+func TestTracerRender(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	ts.strings.mock(`global.Get("document").Get("readyState")`, "complete")
+	ts.strings.mock(`global.Get("document").Call("querySelector", "body").Get("nodeName")`, "BODY")
+	ts.truthies.mock(`global.Get("document").Call("querySelector", "body")`, true)
+
+	tracer := &recordingTracer{}
+	p := NewProgram(nil, WithTracer(tracer))
+	p.traceClock = &stepClock{}
+	inner := &componentFunc{render: func() ComponentOrHTML { return Tag("div") }}
+	renderBody(newBatchRenderer(p), &tracedBody{child: inner}, send)
+
+	want := []string{
+		"render *masc.componentFunc 1ms 1ms",
+		"render *masc.tracedBody 1ms 4ms",
+	}
+	if !reflect.DeepEqual(tracer.events, want) {
+		t.Fatalf("expected events %q, got %q", want, tracer.events)
+	}
+}
+
+type tracedBody struct {
+	Core
+	child Component
+}
+
+func (b *tracedBody) Render(send func(Msg)) ComponentOrHTML { return Tag("body", b.child) }
+
+// TestTracerFakeClock tests that events are timed with the system clock
+// rather than the program's Clock, which does not move when it is fake.
+func TestTracerFakeClock(t *testing.T) {
+	tracer := &recordingTracer{}
+	updated := make(chan struct{})
+	m := &updateFuncModel{update: func(msg Msg) Cmd {
+		if _, ok := msg.(incrementMsg); ok {
+			time.Sleep(time.Millisecond)
+			close(updated)
+		}
+		return nil
+	}}
+	p := NewProgram(m, WithoutRenderer(), WithClock(newFakeClock()), WithTracer(tracer))
+	go func() {
+		p.Send(incrementMsg{})
+		<-updated
+		p.Quit()
+	}()
+	if _, err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	var d time.Duration
+	for _, event := range tracer.events {
+		if took, ok := strings.CutPrefix(event, "update masc.incrementMsg "); ok {
+			d, _ = time.ParseDuration(took)
+		}
+	}
+	if d < time.Millisecond {
+		t.Fatalf("expected the update to take at least 1ms, got %v in %q", d, tracer.events)
+	}
+}
+
+func TestPerformanceTracer(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	ts.floats.mock(`global.Get("performance").Call("now", )`, 100.0)
+	ts.floats.mock(`global.Get("performance").Call("now", )`, 100.0)
+	ts.floats.mock(`global.Get("performance").Call("now", )`, 100.0)
+	ts.floats.mock(`global.Get("performance").Call("now", )`, 100.0)
+
+	var tracer PerformanceTracer
+	tracer.MsgReceived(incrementMsg{})
+	tracer.Update(incrementMsg{}, 2*time.Millisecond)
+	tracer.Render(&tracedBody{}, time.Millisecond, 3*time.Millisecond)
+	tracer.CmdStart(1)
+	tracer.CmdFinish(1, incrementMsg{}, 10*time.Millisecond)
+	tracer.FrameOverrun(20*time.Millisecond, 3)
+}
@@ -0,0 +1,165 @@
+package masc
+
+import "encoding/json"
+
+// TypedModel is the type-safe counterpart of Model: Update returns the
+// model's own type M rather than the Model interface, so that a model cannot
+// accidentally be replaced by a model of another type. Use it with
+// NewTypedProgram.
+//
+// A TypedModel may implement Subscriber, as well as the optional interfaces of
+// a Component such as RenderSkipper, Mounter and Copier. It keeps its state
+// across hot reloads if it implements Snapshot as a Snapshotter does, and
+// Restore returning M. WithPersistence and JSONCodec persist it as they would
+// persist a Model.
+//
+// Example:
+//
+//	type counter struct {
+//	    masc.Core
+//	    n int
+//	}
+//
+//	func (c *counter) Init() masc.Cmd { return nil }
+//
+//	func (c *counter) Update(msg masc.Msg) (*counter, masc.Cmd) {
+//	    if _, ok := msg.(incrementMsg); ok {
+//	        c.n++
+//	    }
+//	    return c, nil
+//	}
+type TypedModel[M any] interface {
+	Component
+
+	// Init is the first function that will be called. It returns an optional
+	// initial command.
+	Init() Cmd
+
+	// Update is called when a message is received and returns the updated
+	// model and an optional command.
+	Update(Msg) (M, Cmd)
+}
+
+// TypedProgram is a Program running a TypedModel. Run returns the final model
+// as its concrete type.
+type TypedProgram[M TypedModel[M]] struct {
+	*Program
+}
+
+// NewTypedProgram creates a new Program running model, a TypedModel. It
+// accepts the same options as NewProgram.
+//
+// Example:
+//
+//	p := masc.NewTypedProgram(&counter{})
+//	final, err := p.Run() // final is a *counter
+func NewTypedProgram[M TypedModel[M]](model M, opts ...ProgramOption) *TypedProgram[M] {
+	return &TypedProgram[M]{Program: NewProgram(&typedModel[M]{model: model}, opts...)}
+}
+
+// Run initializes the program and runs its event loops, blocking until it
+// gets terminated, see [Program.Run]. Returns the final model.
+func (p *TypedProgram[M]) Run() (M, error) {
+	model, err := p.Program.Run()
+	if t, ok := model.(*typedModel[M]); ok {
+		return t.model, err
+	}
+	var zero M
+	return zero, err
+}
+
+// typedModel adapts a TypedModel to the Model interface. It renders the model
+// as its child, so that the optional interfaces of a Component are used as
+// usual, and forwards the ones that the program looks for on models.
+type typedModel[M TypedModel[M]] struct {
+	Core
+	model M
+}
+
+func (t *typedModel[M]) Init() Cmd {
+	return t.model.Init()
+}
+
+func (t *typedModel[M]) Update(msg Msg) (Model, Cmd) {
+	var cmd Cmd
+	t.model, cmd = t.model.Update(msg)
+	return t, cmd
+}
+
+func (t *typedModel[M]) Render(send func(Msg)) ComponentOrHTML {
+	return t.model
+}
+
+// Copy implements Copier by copying the model, so that the debugger records
+// its history and WithUpdateRecovery rolls back panicking updates.
+func (t *typedModel[M]) Copy() Component {
+	return &typedModel[M]{model: copyComponent(t.model).(M)}
+}
+
+// snapshots reports whether the model is a typedSnapshotter.
+func (t *typedModel[M]) snapshots() bool {
+	_, ok := Component(t.model).(typedSnapshotter[M])
+	return ok
+}
+
+// typedAdapter is implemented by typedModel, which implements Snapshotter
+// whether or not the TypedModel it adapts does.
+type typedAdapter interface {
+	snapshots() bool
+}
+
+// asSnapshotter returns model as a Snapshotter, if it implements one.
+func asSnapshotter(model Model) (Snapshotter, bool) {
+	if t, ok := model.(typedAdapter); ok && !t.snapshots() {
+		return nil, false
+	}
+	s, ok := model.(Snapshotter)
+	return s, ok
+}
+
+// Subscriptions implements Subscriber.
+func (t *typedModel[M]) Subscriptions() []Sub {
+	if s, ok := Component(t.model).(Subscriber); ok {
+		return s.Subscriptions()
+	}
+	return nil
+}
+
+// typedSnapshotter is the counterpart of Snapshotter for a TypedModel M.
+type typedSnapshotter[M any] interface {
+	Snapshot() ([]byte, error)
+	Restore(data []byte) (M, error)
+}
+
+// Snapshot implements Snapshotter. It returns nil if the model is not a
+// typedSnapshotter, see asSnapshotter.
+func (t *typedModel[M]) Snapshot() ([]byte, error) {
+	if s, ok := Component(t.model).(typedSnapshotter[M]); ok {
+		return s.Snapshot()
+	}
+	return nil, nil
+}
+
+// Restore implements Snapshotter.
+func (t *typedModel[M]) Restore(data []byte) (Model, error) {
+	s, ok := Component(t.model).(typedSnapshotter[M])
+	if !ok {
+		return t, nil
+	}
+	restored, err := s.Restore(data)
+	if err != nil {
+		return nil, err
+	}
+	return &typedModel[M]{model: restored}, nil
+}
+
+// MarshalJSON encodes the model, so that JSONCodec persists it rather than
+// the adapter.
+func (t *typedModel[M]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.model)
+}
+
+// UnmarshalJSON decodes the model, see MarshalJSON.
+func (t *typedModel[M]) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &t.model)
+}
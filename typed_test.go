@@ -0,0 +1,162 @@
+package masc
+
+import (
+	"strconv"
+	"testing"
+)
+
+type typedCounter struct {
+	Core
+	N int
+}
+
+func (c *typedCounter) Init() Cmd { return nil }
+
+func (c *typedCounter) Update(msg Msg) (*typedCounter, Cmd) {
+	if _, ok := msg.(incrementMsg); ok {
+		// Return a new model, rather than updating c, to check that the
+		// program keeps the returned one.
+		return &typedCounter{N: c.N + 1}, nil
+	}
+	return c, nil
+}
+
+func (c *typedCounter) Render(send func(Msg)) ComponentOrHTML { return Tag("body") }
+
+func (c *typedCounter) Snapshot() ([]byte, error) {
+	return []byte(strconv.Itoa(c.N)), nil
+}
+
+func (c *typedCounter) Restore(data []byte) (*typedCounter, error) {
+	n, err := strconv.Atoi(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return &typedCounter{N: n}, nil
+}
+
+func TestTypedProgram(t *testing.T) {
+	store := MemoryStore()
+	if err := store.Save([]byte(`{"N":5}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewTypedProgram(&typedCounter{}, WithoutRenderer(), WithoutFrameCoalescing(), WithPersistence(store, nil))
+	snapshots := make(chan []byte, 1)
+	go func() {
+		p.Send(incrementMsg{})
+		p.Send(incrementMsg{})
+		data, err := p.snapshot()
+		if err != nil {
+			t.Error(err)
+		}
+		snapshots <- data
+		p.Quit()
+	}()
+	final, err := p.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.N != 7 {
+		t.Fatalf("expected the restored model to be incremented to 7, got %d", final.N)
+	}
+
+	data, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"N":7}` {
+		t.Fatalf("expected the model, not its adapter, to be saved, got %s", data)
+	}
+
+	snapshot := <-snapshots
+	if string(snapshot) != "7" {
+		t.Fatalf("expected a snapshot of the model, got %q", snapshot)
+	}
+	restored, err := restoreSnapshot(&typedModel[*typedCounter]{model: &typedCounter{}}, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := restored.(*typedModel[*typedCounter]).model.N; n != 7 {
+		t.Fatalf("expected the restored model to have N=7, got %d", n)
+	}
+}
+
+func TestTypedProgramKilled(t *testing.T) {
+	p := NewTypedProgram(&typedCounter{}, WithoutRenderer())
+	go p.Kill()
+	final, err := p.Run()
+	if err != ErrProgramKilled {
+		t.Fatalf("expected ErrProgramKilled, got %v", err)
+	}
+	if final == nil {
+		t.Fatal("expected the final model of a killed program")
+	}
+}
+
+// typedSkipper is a TypedModel that implements RenderSkipper and Mounter.
+type typedSkipper struct {
+	Core
+	skip             bool
+	renders, mounted int
+}
+
+func (s *typedSkipper) Init() Cmd                             { return nil }
+func (s *typedSkipper) Update(Msg) (*typedSkipper, Cmd)       { return s, nil }
+func (s *typedSkipper) SkipRender(prev Component) bool        { return s.skip }
+func (s *typedSkipper) Mount()                                { s.mounted++ }
+func (s *typedSkipper) Render(send func(Msg)) ComponentOrHTML { s.renders++; return Tag("body") }
+
+// TestTypedModelRender tests that the optional interfaces of a TypedModel are
+// used when it is rendered.
+func TestTypedModelRender(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.strings.mock(`global.Get("document").Get("readyState")`, "complete")
+	ts.strings.mock(`global.Get("document").Call("querySelector", "body").Get("nodeName")`, "BODY")
+	ts.truthies.mock(`global.Get("document").Call("querySelector", "body")`, true)
+
+	m := &typedSkipper{skip: true}
+	model := &typedModel[*typedSkipper]{model: m}
+	batch := newBatchRenderer(nil)
+	renderBody(batch, model, send)
+	if m.renders != 1 || m.mounted != 1 {
+		t.Fatalf("expected the model to be rendered and mounted once, got %d renders and %d mounts", m.renders, m.mounted)
+	}
+
+	rerender(batch, model, send)
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.invokeCallbackRequestAnimationFrame(0)
+	if m.renders != 1 {
+		t.Fatalf("expected SkipRender to skip the render, got %d renders", m.renders)
+	}
+
+	m.skip = false
+	rerender(batch, model, send)
+	ts.isUndefined.mock(`global.Call("requestAnimationFrame", func)`, 0)
+	ts.invokeCallbackRequestAnimationFrame(0)
+	if m.renders != 2 {
+		t.Fatalf("expected a second render, got %d renders", m.renders)
+	}
+}
+
+// TestTypedModelInterfaces tests that the program only sees the optional
+// interfaces of a model that the TypedModel implements, and that the debugger
+// records copies of it.
+func TestTypedModelInterfaces(t *testing.T) {
+	counter := &typedModel[*typedCounter]{model: &typedCounter{N: 1}}
+	if _, ok := asSnapshotter(counter); !ok {
+		t.Fatal("expected a typedCounter to be a Snapshotter")
+	}
+	cpy := snapshot(counter).(*typedModel[*typedCounter])
+	if cpy.model == counter.model || cpy.model.N != 1 {
+		t.Fatalf("expected a copy of the model, got %+v", cpy.model)
+	}
+
+	skipper := &typedModel[*typedSkipper]{model: &typedSkipper{}}
+	if _, ok := asSnapshotter(skipper); ok {
+		t.Fatal("expected a typedSkipper not to be a Snapshotter")
+	}
+}